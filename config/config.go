@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/IvanWhisper/michelangelo/graceful"
+	"github.com/IvanWhisper/michelangelo/log"
+	"go.uber.org/zap"
+)
+
+// Config
+/**
+ * @Description: application config with typed sections, e.g. app.toml
+ *  [log]
+ *  level = "info"
+ *  [server]
+ *  port = 8080
+ */
+type Config struct {
+	Log    log.Config      `toml:"log" json:"log" yaml:"log"`
+	Server graceful.Config `toml:"server" json:"server" yaml:"server"`
+}
+
+// Default
+/**
+ * @Description: defaults applied before the file, env and flags
+ * @return *Config
+ */
+func Default() *Config {
+	return &Config{
+		Log: log.Config{
+			Level:  "info",
+			Format: "console",
+			File: log.FileLogConfig{
+				MaxSize: 300,
+			},
+		},
+		Server: graceful.Config{
+			Port:            8080,
+			ShutdownTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// Load
+/**
+ * @Description: load path over Default, overlaid by APP_* env variables
+ * @param path
+ * @return *Config
+ * @return error
+ */
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if err := NewLoader(path).Load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Apply
+/**
+ * @Description: initialize the global logger with the log section and build the server
+ * @receiver c
+ * @return *graceful.Server
+ * @return error invalid log section
+ */
+func (c *Config) Apply() (*graceful.Server, error) {
+	if err := log.New(&c.Log); err != nil {
+		return nil, err
+	}
+	return graceful.NewByConfig(&c.Server), nil
+}
+
+// WatchLog
+/**
+ * @Description: apply the log section of the watched file through log.Reload
+ * each time it changes, until ctx is done. Invalid files are logged and the
+ * running logger is kept; changes to other sections leave the logger alone.
+ * @receiver l
+ * @param ctx
+ * @param interval poll interval, default 1s
+ */
+func (l *Loader) WatchLog(ctx context.Context, interval time.Duration) {
+	running := Default()
+	_ = l.Load(running)
+	last := running.Log
+	events := l.Watch(ctx, interval, func() interface{} { return Default() })
+	go func() {
+		for ev := range events {
+			if ev.Err != nil {
+				log.Error("config: log reload rejected, keeping the running config", zap.String("path", ev.Path), zap.Error(ev.Err))
+				continue
+			}
+			next := ev.Value.(*Config).Log
+			if reflect.DeepEqual(next, last) {
+				continue
+			}
+			if err := log.Reload(&next); err == nil {
+				last = next
+			}
+		}
+	}()
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IvanWhisper/michelangelo/log"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestLoad_Formats(t *testing.T) {
+	files := map[string]string{
+		"app.toml": `
+[log]
+level = "debug"
+format = "json"
+[log.file]
+fileName = "app.log"
+maxSize = 10
+[server]
+port = 9090
+readTimeout = "3s"
+`,
+		"app.json": `{"log":{"level":"debug","format":"json","file":{"fileName":"app.log","maxSize":10}},
+"server":{"port":9090,"readTimeout":"3s"}}`,
+		"app.yaml": `
+log:
+  level: debug
+  format: json
+  file:
+    fileName: app.log
+    maxSize: 10
+server:
+  port: 9090
+  readTimeout: 3s
+`,
+	}
+	for name, content := range files {
+		cfg, err := Load(writeFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s => %v", name, err)
+		}
+		if cfg.Log.Level != "debug" || cfg.Log.Format != "json" || cfg.Log.File.FileName != "app.log" || cfg.Log.File.MaxSize != 10 {
+			t.Errorf("%s log => %+v", name, cfg.Log)
+		}
+		if cfg.Server.Port != 9090 || cfg.Server.ReadTimeout != 3*time.Second || cfg.Server.ShutdownTimeout != 5*time.Second {
+			t.Errorf("%s server => %+v", name, cfg.Server)
+		}
+	}
+}
+
+func TestLoad_EnvAndFlags(t *testing.T) {
+	path := writeFile(t, "app.toml", "[log]\nlevel = \"debug\"\n[server]\nport = 9090\n")
+	setenv(t, "APP_LOG_LEVEL", "error")
+	setenv(t, "APP_SERVER_PORT", "7070")
+	setenv(t, "APP_LOG_FILE_COMPRESS", "true")
+	setenv(t, "APP_LOG_MODULES", "billing=debug, orders=warning")
+	// sinks are structs, file only
+	setenv(t, "APP_LOG_SINKS", "file")
+
+	cfg := Default()
+	l := NewLoader(path)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l.BindFlags(fs, cfg)
+	if err := fs.Parse([]string{"-server.port=6060", "-server.idleTimeout", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("log.sinks") != nil || fs.Lookup("log.modules") == nil {
+		t.Error("flags registered for struct slices or missing for maps")
+	}
+	if err := l.Load(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Log.Level != "error" || !cfg.Log.File.Compress {
+		t.Errorf("env overlay => %+v", cfg.Log)
+	}
+	if cfg.Server.Port != 6060 || cfg.Server.IdleTimeout != time.Minute {
+		t.Errorf("flag overlay => %+v", cfg.Server)
+	}
+	if len(cfg.Log.Modules) != 2 || cfg.Log.Modules["billing"] != "debug" || cfg.Log.Modules["orders"] != "warning" {
+		t.Errorf("env modules => %v", cfg.Log.Modules)
+	}
+
+	l, fs = NewLoader(path), flag.NewFlagSet("test", flag.ContinueOnError)
+	l.BindFlags(fs, Default())
+	if err := fs.Parse([]string{"-log.modules=billing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Load(Default()); err == nil || !strings.Contains(err.Error(), "log.modules") {
+		t.Errorf("malformed modules => %v", err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"level.toml":  "[log]\nlevel = \"verbose\"\n",
+		"port.toml":   "[server]\nport = 70000\n",
+		"type.toml":   "[server]\nport = \"http\"\n",
+		"format.conf": "",
+	}
+	for name, content := range cases {
+		if _, err := Load(writeFile(t, name, content)); err == nil {
+			t.Errorf("%s => expect error", name)
+		}
+	}
+}
+
+func TestLoader_Watch(t *testing.T) {
+	path := writeFile(t, "app.toml", "[log]\nlevel = \"info\"\n")
+	l := NewLoader(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := l.Watch(ctx, 10*time.Millisecond, func() interface{} { return Default() })
+
+	if err := ioutil.WriteFile(path, []byte("[log]\nlevel = \"debug\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-events
+	if ev.Err != nil || ev.Value.(*Config).Log.Level != "debug" {
+		t.Fatalf("watch => %+v", ev)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("[log]\nlevel = \"verbose\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ev = <-events; ev.Err == nil {
+		t.Errorf("watch invalid => %+v", ev)
+	}
+	cancel()
+	for range events {
+	}
+}
+
+func TestLoader_WatchLog(t *testing.T) {
+	path := writeFile(t, "app.toml", "[log]\nlevel = \"info\"\nstdLevel = \"critical\"\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.New(nil) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NewLoader(path).WatchLog(ctx, 10*time.Millisecond)
+
+	waitLevel := func(want string) bool {
+		for i := 0; i < 100; i++ {
+			if log.GetLevel().String() == want {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	if err := ioutil.WriteFile(path, []byte("[log]\nlevel = \"debug\"\nstdLevel = \"critical\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !waitLevel("debug") {
+		t.Fatalf("level => %s", log.GetLevel())
+	}
+
+	props := log.GetProps()
+	if err := ioutil.WriteFile(path, []byte("[log]\nlevel = \"verbose\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if log.GetProps() != props || log.GetLevel().String() != "debug" {
+		t.Errorf("invalid file replaced the logger, level %s", log.GetLevel())
+	}
+}
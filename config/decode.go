@@ -0,0 +1,388 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldKey name of a struct field in config files, taken from the json tag
+func fieldKey(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// lookup find key in m, case-insensitive
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// decode
+/**
+ * @Description: assign a normalized tree (maps, slices, scalars) onto dst.
+ * Strings are converted to the target kind so that env and flag values share
+ * the file path; durations accept "1m30s" or nanoseconds.
+ * @param src
+ * @param dst
+ * @param path used in error messages
+ * @return error
+ */
+func decode(src interface{}, dst reflect.Value, path string) error {
+	if src == nil {
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decode(src, dst.Elem(), path)
+	}
+	if s, ok := src.(string); ok && dst.Type() != durationType && dst.CanAddr() && dst.Addr().Type().Implements(textUnmarshalerType) {
+		if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+	if dst.Type() == durationType {
+		switch v := src.(type) {
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			dst.SetInt(int64(d))
+			return nil
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		switch v := src.(type) {
+		case string:
+			dst.SetString(v)
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("%s: expect string, got %T", path, src)
+		default:
+			dst.SetString(fmt.Sprint(v))
+		}
+	case reflect.Bool:
+		switch v := src.(type) {
+		case bool:
+			dst.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			dst.SetBool(b)
+		default:
+			return fmt.Errorf("%s: expect bool, got %T", path, src)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%s: %d overflows %s", path, n, dst.Type())
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s: %d overflows %s", path, n, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		dst.SetFloat(f)
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expect table, got %T", path, src)
+		}
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				if err := decode(m, dst.Field(i), path); err != nil {
+					return err
+				}
+				continue
+			}
+			key, ok := fieldKey(f)
+			if !ok {
+				continue
+			}
+			if v, found := lookup(m, key); found {
+				if err := decode(v, dst.Field(i), join(path, key)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		var m map[string]interface{}
+		switch v := src.(type) {
+		case map[string]interface{}:
+			m = v
+		case string:
+			// env and flags: comma separated key=value pairs
+			pairs, err := parsePairs(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			m = pairs
+		default:
+			return fmt.Errorf("%s: expect table, got %T", path, src)
+		}
+		if dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: map key must be string", path)
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for k, v := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if old := dst.MapIndex(reflect.ValueOf(k).Convert(dst.Type().Key())); old.IsValid() {
+				elem.Set(old)
+			}
+			if err := decode(v, elem, join(path, k)); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+	case reflect.Slice:
+		var items []interface{}
+		switch v := src.(type) {
+		case []interface{}:
+			items = v
+		case string:
+			// env and flags: comma separated
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, s)
+				}
+			}
+		default:
+			return fmt.Errorf("%s: expect array, got %T", path, src)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(src))
+	default:
+		return fmt.Errorf("%s: unsupported type %s", path, dst.Type())
+	}
+	return nil
+}
+
+// parsePairs table of "a=1, b=2"
+func parsePairs(s string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("expect key=value, got %q", pair)
+		}
+		m[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return m, nil
+}
+
+func toInt(src interface{}) (int64, error) {
+	switch v := src.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("expect integer, got %v", v)
+		}
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		return 0, fmt.Errorf("expect integer, got %T", src)
+	}
+}
+
+func toFloat(src interface{}) (float64, error) {
+	switch v := src.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("expect number, got %T", src)
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// normalize convert decoder output into map[string]interface{} / []interface{} trees
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = normalize(item)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case []map[string]interface{}:
+		items := make([]interface{}, len(t))
+		for i, item := range t {
+			items[i] = normalize(item)
+		}
+		return items
+	case []interface{}:
+		for i, item := range t {
+			t[i] = normalize(item)
+		}
+		return t
+	case int:
+		return int64(t)
+	default:
+		return v
+	}
+}
+
+// merge deep merge src into dst, src wins
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sm, ok := v.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				merge(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// setPath set value at a dotted path, creating tables on the way
+func setPath(tree map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := tree[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			tree[key] = next
+		}
+		tree = next
+	}
+	tree[path[len(path)-1]] = value
+}
+
+// leaves list key paths of scalar fields and of slices and string keyed maps
+// of scalars in t, used by env and flags. Slices and maps of structs take no
+// single string, they are only set from the file.
+func leaves(t reflect.Type, prefix []string) [][]string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == durationType {
+		return [][]string{prefix}
+	}
+	var paths [][]string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			paths = append(paths, leaves(f.Type, prefix)...)
+			continue
+		}
+		key, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+		if f.Type.Kind() == reflect.Map {
+			if f.Type.Key().Kind() == reflect.String && scalar(f.Type.Elem()) {
+				paths = append(paths, append(append([]string{}, prefix...), key))
+			}
+			continue
+		}
+		if textUnmarshaler(f.Type) {
+			paths = append(paths, append(append([]string{}, prefix...), key))
+			continue
+		}
+		if f.Type.Kind() == reflect.Slice {
+			if scalar(f.Type.Elem()) || textUnmarshaler(f.Type.Elem()) {
+				paths = append(paths, append(append([]string{}, prefix...), key))
+			}
+			continue
+		}
+		paths = append(paths, leaves(f.Type, append(append([]string{}, prefix...), key))...)
+	}
+	return paths
+}
+
+// scalar values parsed from a single string
+func scalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// textUnmarshaler values of t parse themselves from a string
+func textUnmarshaler(t reflect.Type) bool {
+	return t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
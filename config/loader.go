@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Validator implemented by config sections, see log.Config and graceful.Config
+type Validator interface {
+	Validate() error
+}
+
+// Loader
+/**
+ * @Description: loads a value from a config file, then overlays environment
+ * variables and command-line flags, in that order of precedence.
+ * Keys are the json tag names of the target struct, e.g. log.file.maxSize
+ * is set by APP_LOG_FILE_MAXSIZE or -log.file.maxSize.
+ */
+type Loader struct {
+	Path      string // config file, format by extension: .toml .json .yaml .yml; empty skips the file
+	EnvPrefix string // e.g. APP, empty disables the env overlay
+
+	flags     *flag.FlagSet
+	flagPaths map[string][]string
+}
+
+// NewLoader
+/**
+ * @Description: loader with the APP env prefix
+ * @param path
+ * @return *Loader
+ */
+func NewLoader(path string) *Loader {
+	return &Loader{Path: path, EnvPrefix: "APP"}
+}
+
+// BindFlags
+/**
+ * @Description: register one string flag per config key on fs, call before fs.Parse.
+ * Only flags set on the command line override the file and env.
+ * @receiver l
+ * @param fs
+ * @param v pointer to the config struct
+ */
+func (l *Loader) BindFlags(fs *flag.FlagSet, v interface{}) {
+	l.flags = fs
+	l.flagPaths = make(map[string][]string)
+	for _, path := range leaves(reflect.TypeOf(v), nil) {
+		name := strings.Join(path, ".")
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		usage := name
+		if l.EnvPrefix != "" {
+			usage = fmt.Sprintf("%s (env %s)", name, l.envName(path))
+		}
+		fs.String(name, "", usage)
+		l.flagPaths[name] = path
+	}
+}
+
+// Load
+/**
+ * @Description: fill v from file, env and flags, then validate every section
+ * @receiver l
+ * @param v pointer to the config struct, preset fields act as defaults
+ * @return error
+ */
+func (l *Loader) Load(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("config: Load needs a non-nil pointer")
+	}
+	tree := make(map[string]interface{})
+	if l.Path != "" {
+		raw, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return err
+		}
+		fileTree, err := parse(l.Path, raw)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", l.Path, err)
+		}
+		merge(tree, fileTree)
+	}
+	merge(tree, l.envTree(rv.Type()))
+	merge(tree, l.flagTree())
+	if err := decode(tree, rv, ""); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return validate(rv)
+}
+
+func (l *Loader) envName(path []string) string {
+	return strings.ToUpper(l.EnvPrefix + "_" + strings.Join(path, "_"))
+}
+
+func (l *Loader) envTree(t reflect.Type) map[string]interface{} {
+	tree := make(map[string]interface{})
+	if l.EnvPrefix == "" {
+		return tree
+	}
+	for _, path := range leaves(t, nil) {
+		if v, ok := os.LookupEnv(l.envName(path)); ok {
+			setPath(tree, path, v)
+		}
+	}
+	return tree
+}
+
+func (l *Loader) flagTree() map[string]interface{} {
+	tree := make(map[string]interface{})
+	if l.flags == nil {
+		return tree
+	}
+	l.flags.Visit(func(f *flag.Flag) {
+		if path, ok := l.flagPaths[f.Name]; ok {
+			setPath(tree, path, f.Value.String())
+		}
+	})
+	return tree
+}
+
+// parse decode raw by file extension into a normalized tree
+func parse(path string, raw []byte) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		if _, err := toml.Decode(string(raw), &tree); err != nil {
+			return nil, err
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if err := dec.Decode(&tree); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(raw, &tree); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format '%s'", filepath.Ext(path))
+	}
+	return normalize(tree).(map[string]interface{}), nil
+}
+
+// validate call Validate on v and every nested struct implementing Validator
+func validate(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if v.CanAddr() {
+		if val, ok := v.Addr().Interface().(Validator); ok {
+			if err := val.Validate(); err != nil {
+				return fmt.Errorf("config: %w", err)
+			}
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if err := validate(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
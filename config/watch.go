@@ -0,0 +1,74 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"time"
+)
+
+// Event published by Watch after the config file changed
+type Event struct {
+	Path  string
+	Value interface{} // freshly loaded and validated value, nil when Err is set
+	Err   error       // read, parse or validation error, the previous value stays in effect
+}
+
+// Watch
+/**
+ * @Description: poll the config file every interval and publish an Event
+ * each time its content changes. The channel is closed when ctx is done.
+ * @receiver l
+ * @param ctx
+ * @param interval
+ * @param newValue returns a fresh value with defaults to load into
+ * @return <-chan Event
+ */
+func (l *Loader) Watch(ctx context.Context, interval time.Duration, newValue func() interface{}) <-chan Event {
+	events := make(chan Event, 1)
+	if interval <= 0 {
+		interval = time.Second
+	}
+	last := l.fingerprint()
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			sum := l.fingerprint()
+			if sum == nil || bytes.Equal(sum, last) {
+				continue
+			}
+			last = sum
+			ev := Event{Path: l.Path}
+			v := newValue()
+			if err := l.Load(v); err != nil {
+				ev.Err = err
+			} else {
+				ev.Value = v
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// fingerprint content hash of the file, nil while it cannot be read
+func (l *Loader) fingerprint() []byte {
+	raw, err := ioutil.ReadFile(l.Path)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(raw)
+	return sum[:]
+}
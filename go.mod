@@ -3,14 +3,15 @@ module github.com/IvanWhisper/michelangelo
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/gin-gonic/gin v1.6.3
 	github.com/google/uuid v1.2.0
-	github.com/jonboulle/clockwork v0.2.2 // indirect
-	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible
-	github.com/lestrrat-go/strftime v1.0.4 // indirect
-	github.com/pkg/errors v0.8.1
-	github.com/stretchr/testify v1.7.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
 	go.uber.org/zap v1.16.0
-	gopkg.in/natefinch/lumberjack.v2 v2.0.0
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974
+	google.golang.org/grpc v1.46.2
+	gopkg.in/yaml.v2 v2.2.8
+	gorm.io/gorm v1.21.16
 	xorm.io/xorm v1.0.7
 )
@@ -0,0 +1,118 @@
+package graceful
+
+import (
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+
+	"github.com/IvanWhisper/michelangelo/environment"
+	mlog "github.com/IvanWhisper/michelangelo/log"
+)
+
+// unixAddrPrefix marks an admin address as a unix socket path, e.g. unix:/run/app/admin.sock
+const unixAddrPrefix = "unix:"
+
+// AdminHandler
+/**
+ * @Description: operational endpoints that must never be mounted on the public engine
+ *  /debug/pprof/*  net/http/pprof
+ *  /debug/vars     expvar
+ *  /loglevel       GET current level, PUT new level, ?module= for Named loggers
+ *  /version        build metadata
+ * @return http.Handler
+ */
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/loglevel", logLevelHandler)
+	mux.HandleFunc("/version", versionHandler)
+	return mux
+}
+
+type logLevelBody struct {
+	Module string `json:"module,omitempty"`
+	Level  string `json:"level"`
+}
+
+// logLevelHandler accept a plain text level or {"level":"debug"},
+// ?module=billing or {"module":"billing"} targets a Named logger,
+// an empty module level makes it follow the global level again
+func logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	module := r.URL.Query().Get("module")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		raw, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1024))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body := logLevelBody{Module: module, Level: strings.TrimSpace(string(raw))}
+		if strings.HasPrefix(body.Level, "{") {
+			body.Level = ""
+			if err := json.Unmarshal(raw, &body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		module = body.Module
+		if module != "" {
+			if err := mlog.SetModuleLevel(module, body.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			mlog.Info("Admin: log level of " + module + " changed to " + body.Level)
+			break
+		}
+		if err := mlog.SetLevel(body.Level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mlog.Info("Admin: log level changed to " + mlog.GetLevel().String())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if module != "" {
+		resp := logLevelBody{Module: module}
+		if lv, ok := mlog.GetModuleLevel(module); ok {
+			resp.Level = lv.String()
+		}
+		writeJSON(w, resp)
+		return
+	}
+	writeJSON(w, logLevelBody{Level: mlog.GetLevel().String()})
+}
+
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, environment.GetBuildInfo())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// listenAdmin bind a tcp address or a unix socket
+func listenAdmin(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, unixAddrPrefix) {
+		path := strings.TrimPrefix(addr, unixAddrPrefix)
+		// remove a stale socket left by a previous process
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
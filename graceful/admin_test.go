@@ -0,0 +1,124 @@
+package graceful
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IvanWhisper/michelangelo/environment"
+	mlog "github.com/IvanWhisper/michelangelo/log"
+)
+
+func TestAdminHandler_LogLevel(t *testing.T) {
+	mlog.New(nil)
+	h := AdminHandler()
+
+	for _, body := range []string{"debug", `{"level":"error"}`} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PUT %s => %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if lv := mlog.GetLevel().String(); lv != "error" {
+		t.Errorf("level => %s!=error", lv)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader("verbose")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid level => %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/loglevel", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST => %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"module":"billing","level":"debug"}`)))
+	if lv, ok := mlog.GetModuleLevel("billing"); rec.Code != http.StatusOK || !ok || lv.String() != "debug" {
+		t.Errorf("PUT module => %d %s", rec.Code, rec.Body.String())
+	}
+	if lv := mlog.GetLevel().String(); lv != "error" {
+		t.Errorf("module change altered global level => %s", lv)
+	}
+	mlog.SetLevel("info")
+}
+
+func TestAdminHandler_Endpoints(t *testing.T) {
+	h := AdminHandler()
+	for _, path := range []string{"/version", "/debug/vars", "/debug/pprof/"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s => %d", path, rec.Code)
+		}
+	}
+}
+
+func TestServer_Run_AdminUnixSocket(t *testing.T) {
+	mlog.New(nil)
+	sock := filepath.Join(t.TempDir(), "admin.sock")
+	s := New()
+	s.Ip = "127.0.0.1"
+	s.AdminAddr = unixAddrPrefix + sock
+	s.ReadHeaderTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://admin/version"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var info environment.BuildInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.GoVersion == "" {
+		t.Errorf("version => %+v", info)
+	}
+
+	// a client that never finishes its headers is cut off like on the main port
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("GET /version HTTP/1.1\r\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("slow admin client => %v", err)
+	}
+}
@@ -28,7 +28,7 @@ func TestApplication_Run_PrintCh(t *testing.T) {
 	}
 }
 
-func ExampleApplication_Run() {
+func ExampleIApplication_Run() {
 	app := NewIApplication(
 		WithCmd("go"),
 		WithPrintCh(),
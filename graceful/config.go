@@ -0,0 +1,73 @@
+package graceful
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config
+/**
+ * @Description: serializes server related config in toml/json/yaml.
+ */
+type Config struct {
+	Name              string        `toml:"name" json:"name" yaml:"name"`
+	Ip                string        `toml:"ip" json:"ip" yaml:"ip"`
+	Port              int           `toml:"port" json:"port" yaml:"port"`
+	AdminAddr         string        `toml:"adminAddr" json:"adminAddr" yaml:"adminAddr"`       // "127.0.0.1:6060" or "unix:/path", empty disables it.
+	MaxConns          int           `toml:"maxConns" json:"maxConns" yaml:"maxConns"`          // 0 is unlimited.
+	MaxInFlight       int           `toml:"maxInFlight" json:"maxInFlight" yaml:"maxInFlight"` // 0 is unlimited.
+	ReadTimeout       time.Duration `toml:"readTimeout" json:"readTimeout" yaml:"readTimeout"`
+	ReadHeaderTimeout time.Duration `toml:"readHeaderTimeout" json:"readHeaderTimeout" yaml:"readHeaderTimeout"`
+	WriteTimeout      time.Duration `toml:"writeTimeout" json:"writeTimeout" yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `toml:"idleTimeout" json:"idleTimeout" yaml:"idleTimeout"`
+	ShutdownTimeout   time.Duration `toml:"shutdownTimeout" json:"shutdownTimeout" yaml:"shutdownTimeout"`
+}
+
+// Validate
+/**
+ * @Description: check value ranges
+ * @receiver c
+ * @return error
+ */
+func (c *Config) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("server.port %d out of range", c.Port)
+	}
+	if c.MaxConns < 0 || c.MaxInFlight < 0 {
+		return fmt.Errorf("server.maxConns/maxInFlight must not be negative")
+	}
+	for name, d := range map[string]time.Duration{
+		"readTimeout":       c.ReadTimeout,
+		"readHeaderTimeout": c.ReadHeaderTimeout,
+		"writeTimeout":      c.WriteTimeout,
+		"idleTimeout":       c.IdleTimeout,
+		"shutdownTimeout":   c.ShutdownTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("server.%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// NewByConfig
+/**
+ * @Description: create a server with a new gin engine from config
+ * @param cfg
+ * @return *Server
+ */
+func NewByConfig(cfg *Config) *Server {
+	s := New()
+	s.Name = cfg.Name
+	s.Ip = cfg.Ip
+	s.Port = cfg.Port
+	s.AdminAddr = cfg.AdminAddr
+	s.MaxConns = cfg.MaxConns
+	s.MaxInFlight = cfg.MaxInFlight
+	s.ReadTimeout = cfg.ReadTimeout
+	s.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+	s.WriteTimeout = cfg.WriteTimeout
+	s.IdleTimeout = cfg.IdleTimeout
+	s.ShutdownTimeout = cfg.ShutdownTimeout
+	return s
+}
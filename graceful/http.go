@@ -2,9 +2,14 @@ package graceful
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	mlog "github.com/IvanWhisper/michelangelo/log"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
@@ -16,6 +21,27 @@ type Server struct {
 	Ip     string
 	Port   int
 	Engine *gin.Engine
+
+	// GRPC served on the same port as Engine, optional.
+	// gRPC clients use h2c prior knowledge, REST stays on Engine.
+	GRPC *grpc.Server
+
+	// AdminAddr separate listener for pprof, expvar, log level and version,
+	// "127.0.0.1:6060" or "unix:/run/app/admin.sock", empty disables it.
+	AdminAddr string
+
+	// MaxConns caps concurrent connections, excess HTTP/1.x connections receive 503, h2c ones are closed. 0 is unlimited.
+	MaxConns int
+	// MaxInFlight caps concurrent requests, excess requests receive 503. 0 is unlimited.
+	MaxInFlight int
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration // keep-alive idle timeout
+	ShutdownTimeout   time.Duration // default 5s
+
+	inFlight requestTracker
 }
 
 func New() *Server {
@@ -24,38 +50,232 @@ func New() *Server {
 	}
 }
 
+// Start
+/**
+ * @Description: run the server until a stop signal arrives
+ */
 func (s *Server) Start() {
-	// bind ip&port
-	srv := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.Ip, s.Port),
-		Handler: s.Engine,
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+	if err := s.Run(ctx); err != nil {
+		mlog.Error(fmt.Sprintf("Listen: Receive Error %s", err.Error()))
 	}
+}
 
-	errCh := make(chan error, 1)
-	defer close(errCh)
-	// listen
-	go func() {
-		// service connections
-		if err := srv.ListenAndServe(); err != nil {
-			errCh <- err
+// Run
+/**
+ * @Description: serve until ctx is done, then shutdown gracefully.
+ * Listeners passed by systemd socket activation are used when present,
+ * and READY/STOPPING/WATCHDOG states are sent to NOTIFY_SOCKET.
+ * @param ctx
+ * @return error
+ */
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve
+/**
+ * @Description: like Run, on a listener provided by the caller
+ * @param ctx
+ * @param ln
+ * @return error
+ */
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.MaxConns > 0 {
+		ln = newLimitListener(ln, s.MaxConns)
+	}
+	handler := s.inFlight.handler(s.Engine, s.MaxInFlight)
+	var mux *connMux
+	if s.GRPC != nil {
+		mux = newConnMux(ln, s.ReadHeaderTimeout)
+		ln = mux.http
+		// REST over h2c
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: s.IdleTimeout})
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       s.ReadTimeout,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+	}
+	servers := []*http.Server{srv}
+	listeners := []net.Listener{ln}
+	if s.AdminAddr != "" {
+		adminLn, err := listenAdmin(s.AdminAddr)
+		if err != nil {
+			_ = ln.Close()
+			return err
 		}
-	}()
+		mlog.Info(fmt.Sprintf("Listen: admin %s", adminLn.Addr()))
+		// no WriteTimeout, pprof profiles and traces stream for as long as asked
+		servers = append(servers, &http.Server{
+			Handler:           AdminHandler(),
+			ReadTimeout:       s.ReadTimeout,
+			ReadHeaderTimeout: s.ReadHeaderTimeout,
+			IdleTimeout:       s.IdleTimeout,
+		})
+		listeners = append(listeners, adminLn)
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	errCh := make(chan error, len(servers)+1)
+	if mux != nil {
+		go func() {
+			if err := s.GRPC.Serve(mux.grpc); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				errCh <- err
+			}
+		}()
+	}
+	// listen
+	for i := range servers {
+		go func(srv *http.Server, ln net.Listener) {
+			// service connections
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}(servers[i], listeners[i])
+	}
+	s.notify(SdReady)
+	stopWatchdog := s.watchdog()
+	defer stopWatchdog()
 
 	select {
-	case s := <-ctx.Done():
-		stop()
-		mlog.Info(fmt.Sprintf("Shutdown: Receive Sign(%s)", s))
-		timeoutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	case <-ctx.Done():
+		mlog.Info(fmt.Sprintf("Shutdown: Receive Sign(%s)", ctx.Err()))
+		s.notify(SdStopping)
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
 		defer cancel()
-		if err := srv.Shutdown(timeoutCtx); err != nil {
-			mlog.Error(fmt.Sprintf("Shutdown: %s", err.Error()))
+		if mux != nil {
+			_ = mux.Close()
+		}
+		grpcDone := s.stopGRPC(timeoutCtx)
+		for _, srv := range servers {
+			if err := srv.Shutdown(timeoutCtx); err != nil {
+				mlog.Error(fmt.Sprintf("Shutdown: %s", err.Error()))
+			}
+		}
+		<-grpcDone
+		if timeoutCtx.Err() != nil {
+			for _, r := range s.ActiveRequests() {
+				mlog.Warn(fmt.Sprintf("Shutdown: still in flight %s %s for %s", r.Method, r.Path, time.Since(r.Start)))
+			}
 		}
 		mlog.Info("Shutdown: exit")
-		break
+		return nil
 	case e := <-errCh:
-		mlog.Error(fmt.Sprintf("Listen: Receive Error %s", e.Error()))
-		break
+		s.notify(SdStopping)
+		if mux != nil {
+			_ = mux.Close()
+			s.GRPC.Stop()
+		}
+		for _, srv := range servers {
+			_ = srv.Close()
+		}
+		return e
+	}
+}
+
+// stopGRPC GracefulStop the gRPC server, force Stop when ctx expires first
+func (s *Server) stopGRPC(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if s.GRPC == nil {
+		close(done)
+		return done
 	}
+	stopped := make(chan struct{})
+	go func() {
+		s.GRPC.GracefulStop()
+		close(stopped)
+	}()
+	go func() {
+		defer close(done)
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			mlog.Error("Shutdown: grpc graceful stop timeout")
+			s.GRPC.Stop()
+			<-stopped
+		}
+	}()
+	return done
+}
+
+// InFlight number of requests being served
+func (s *Server) InFlight() int {
+	s.inFlight.mu.Lock()
+	defer s.inFlight.mu.Unlock()
+	return len(s.inFlight.active)
+}
+
+// ActiveRequests requests being served, oldest first
+func (s *Server) ActiveRequests() []ActiveRequest {
+	return s.inFlight.snapshot()
+}
+
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.ShutdownTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return s.ShutdownTimeout
+}
+
+// listen use the systemd socket when activated, otherwise bind ip&port
+func (s *Server) listen() (net.Listener, error) {
+	listeners, names, err := SdListeners(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) > 0 {
+		ln := listeners[0]
+		for i, name := range names {
+			if name == s.Name {
+				ln = listeners[i]
+			}
+		}
+		for _, l := range listeners {
+			if l != ln {
+				_ = l.Close()
+			}
+		}
+		mlog.Info(fmt.Sprintf("Listen: systemd socket %s", ln.Addr()))
+		return ln, nil
+	}
+	return net.Listen("tcp", fmt.Sprintf("%s:%d", s.Ip, s.Port))
+}
+
+// notify send state to systemd, failures are only logged
+func (s *Server) notify(state string) {
+	if _, err := SdNotify(state); err != nil {
+		mlog.Warn(fmt.Sprintf("Notify: %s %s", state, err.Error()))
+	}
+}
+
+// watchdog keep alive systemd watchdog at half of WATCHDOG_USEC
+func (s *Server) watchdog() func() {
+	interval, err := SdWatchdogEnabled()
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Watchdog: %s", err.Error()))
+	}
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.notify(SdWatchdog)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
 }
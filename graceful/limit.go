@@ -0,0 +1,148 @@
+package graceful
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	// rawServiceUnavailable answer written to HTTP/1.x connections refused by the connection cap
+	rawServiceUnavailable = "HTTP/1.1 503 Service Unavailable\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"
+	// rejectTimeout bound for reading the first bytes of and answering a refused connection
+	rejectTimeout = time.Second
+)
+
+// limitListener
+/**
+ * @Description: caps concurrent connections, HTTP/1.x connections over the cap
+ * receive 503, h2c and gRPC ones are closed as they cannot read it
+ */
+type limitListener struct {
+	net.Listener
+	max    int64
+	active int64
+}
+
+func newLimitListener(l net.Listener, max int) net.Listener {
+	return &limitListener{Listener: l, max: int64(max)}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if atomic.AddInt64(&l.active, 1) > l.max {
+			atomic.AddInt64(&l.active, -1)
+			go reject(c)
+			continue
+		}
+		return &limitConn{Conn: c, release: func() { atomic.AddInt64(&l.active, -1) }}, nil
+	}
+}
+
+func reject(c net.Conn) {
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(rejectTimeout))
+	if isHTTP1(c) {
+		_, _ = c.Write([]byte(rawServiceUnavailable))
+	}
+}
+
+// isHTTP1 the first bytes of c leave the HTTP/2 connection preface,
+// false for h2c and for clients sending nothing in time
+func isHTTP1(c net.Conn) bool {
+	br := bufio.NewReader(c)
+	for n := 1; n <= len(http2.ClientPreface); n++ {
+		peek, err := br.Peek(n)
+		if err != nil {
+			return false
+		}
+		if peek[n-1] != http2.ClientPreface[n-1] {
+			return true
+		}
+	}
+	return false
+}
+
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// ActiveRequest request still being served
+type ActiveRequest struct {
+	Method string
+	Path   string
+	Start  time.Time
+}
+
+// requestTracker
+/**
+ * @Description: counts in-flight requests and rejects requests over max with 503
+ */
+type requestTracker struct {
+	mu     sync.Mutex
+	seq    uint64
+	active map[uint64]ActiveRequest
+}
+
+func (t *requestTracker) add(r *http.Request, max int) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if max > 0 && len(t.active) >= max {
+		return 0, false
+	}
+	if t.active == nil {
+		t.active = make(map[uint64]ActiveRequest)
+	}
+	t.seq++
+	t.active[t.seq] = ActiveRequest{Method: r.Method, Path: r.URL.Path, Start: time.Now()}
+	return t.seq, true
+}
+
+func (t *requestTracker) done(id uint64) {
+	t.mu.Lock()
+	delete(t.active, id)
+	t.mu.Unlock()
+}
+
+// snapshot active requests, oldest first
+func (t *requestTracker) snapshot() []ActiveRequest {
+	t.mu.Lock()
+	reqs := make([]ActiveRequest, 0, len(t.active))
+	for _, r := range t.active {
+		reqs = append(reqs, r)
+	}
+	t.mu.Unlock()
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].Start.Before(reqs[j].Start) })
+	return reqs
+}
+
+func (t *requestTracker) handler(next http.Handler, max int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := t.add(r, max)
+		if !ok {
+			w.Header().Set("Connection", "close")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer t.done(id)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,102 @@
+package graceful
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestLimitListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go func() { _ = srv.Serve(newLimitListener(ln, 1)) }()
+	defer srv.Close()
+
+	first, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	// make sure the first connection has been accepted
+	if _, err := first.Write([]byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := http.ReadResponse(bufio.NewReader(first), nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("first connection => %v %v", resp, err)
+	}
+
+	second, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if _, err := second.Write([]byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(second), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("second connection => %d!=503", resp.StatusCode)
+	}
+
+	// h2c clients would take the HTTP/1.1 answer for a broken frame, they are only closed
+	third, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	if _, err := third.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadAll(third); err != nil || len(data) != 0 {
+		t.Errorf("h2c connection => %q %v", data, err)
+	}
+}
+
+func TestRequestTracker(t *testing.T) {
+	var (
+		tracker requestTracker
+		entered = make(chan struct{})
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	h := tracker.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}), 1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-entered
+
+	active := tracker.snapshot()
+	if len(active) != 1 || active[0].Path != "/slow" || active[0].Method != http.MethodGet {
+		t.Fatalf("snapshot => %+v", active)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "Service Unavailable") {
+		t.Errorf("over cap => %d", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if n := len(tracker.snapshot()); n != 0 {
+		t.Errorf("in flight after done => %d", n)
+	}
+}
@@ -0,0 +1,239 @@
+package graceful
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	// sniffTimeout bound for reading the first request of a connection
+	sniffTimeout = 10 * time.Second
+	// sniffMaxFrames guard against clients that never send HEADERS
+	sniffMaxFrames = 32
+)
+
+// connMux
+/**
+ * @Description: splits one listener into a gRPC and an HTTP listener.
+ * HTTP/2 prior-knowledge connections (h2c) whose first request carries
+ * content-type application/grpc go to gRPC, everything else goes to HTTP.
+ */
+type connMux struct {
+	root net.Listener
+	grpc *muxListener
+	http *muxListener
+}
+
+func newConnMux(root net.Listener, timeout time.Duration) *connMux {
+	if timeout <= 0 {
+		timeout = sniffTimeout
+	}
+	m := &connMux{
+		root: root,
+		grpc: newMuxListener(root.Addr()),
+		http: newMuxListener(root.Addr()),
+	}
+	go m.serve(timeout)
+	return m
+}
+
+func (m *connMux) serve(timeout time.Duration) {
+	for {
+		c, err := m.root.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() { //nolint:staticcheck
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			// the servers on the child listeners return err from Serve
+			m.grpc.closeWith(err)
+			m.http.closeWith(err)
+			return
+		}
+		go m.dispatch(c, timeout)
+	}
+}
+
+func (m *connMux) dispatch(c net.Conn, timeout time.Duration) {
+	_ = c.SetReadDeadline(time.Now().Add(timeout))
+	sc, isGRPC, err := sniff(c)
+	if err != nil {
+		_ = c.Close()
+		return
+	}
+	_ = c.SetReadDeadline(time.Time{})
+	target := m.http
+	if isGRPC {
+		target = m.grpc
+	}
+	if !target.push(sc) {
+		_ = c.Close()
+	}
+}
+
+// Close stop accepting, child listeners are closed by their servers
+func (m *connMux) Close() error {
+	return m.root.Close()
+}
+
+// sniff peek the connection preface and the first HEADERS frame.
+// The server SETTINGS frame is sent up front because some gRPC clients wait
+// for it before sending any request; the matching SETTINGS ACK is removed
+// from the replayed bytes so the real server never sees it.
+func sniff(c net.Conn) (net.Conn, bool, error) {
+	br := bufio.NewReader(c)
+	// compare byte by byte so short HTTP/1.x requests are not held back
+	for n := 1; n <= len(http2.ClientPreface); n++ {
+		peek, err := br.Peek(n)
+		if err != nil || peek[n-1] != http2.ClientPreface[n-1] {
+			// plain HTTP/1.x, or a client that closed early: let the HTTP server deal with it
+			return &replayConn{Conn: c, r: br}, false, nil
+		}
+	}
+	var replay bytes.Buffer
+	if _, err := io.CopyN(&replay, br, int64(len(http2.ClientPreface))); err != nil {
+		return nil, false, err
+	}
+	if err := http2.NewFramer(c, nil).WriteSettings(); err != nil {
+		return nil, false, err
+	}
+
+	var (
+		contentType string
+		headersDone bool
+		ackDropped  bool
+		block       []byte
+		header      [9]byte
+	)
+	for i := 0; i < sniffMaxFrames && !(headersDone && ackDropped); i++ {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return nil, false, err
+		}
+		length := int(header[0])<<16 | int(header[1])<<8 | int(header[2])
+		typ, flags := http2.FrameType(header[3]), http2.Flags(header[4])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return nil, false, err
+		}
+		if typ == http2.FrameSettings && flags.Has(http2.FlagSettingsAck) && !ackDropped {
+			ackDropped = true
+			continue
+		}
+		replay.Write(header[:])
+		replay.Write(payload)
+
+		switch {
+		case typ == http2.FrameHeaders && !headersDone:
+			block = append(block, headerFragment(payload, flags)...)
+			headersDone = flags.Has(http2.FlagHeadersEndHeaders)
+		case typ == http2.FrameContinuation && block != nil && !headersDone:
+			block = append(block, payload...)
+			headersDone = flags.Has(http2.FlagContinuationEndHeaders)
+		}
+		if headersDone && contentType == "" {
+			contentType = decodeContentType(block)
+		}
+	}
+	if !headersDone {
+		return nil, false, errors.New("mux: no HEADERS frame from http2 client")
+	}
+	return &replayConn{Conn: c, r: io.MultiReader(&replay, br)}, strings.HasPrefix(contentType, "application/grpc"), nil
+}
+
+// headerFragment strip padding and priority from a HEADERS payload
+func headerFragment(payload []byte, flags http2.Flags) []byte {
+	var pad int
+	if flags.Has(http2.FlagHeadersPadded) && len(payload) > 0 {
+		pad = int(payload[0])
+		payload = payload[1:]
+	}
+	if flags.Has(http2.FlagHeadersPriority) && len(payload) >= 5 {
+		payload = payload[5:]
+	}
+	if pad > len(payload) {
+		return nil
+	}
+	return payload[:len(payload)-pad]
+}
+
+func decodeContentType(block []byte) string {
+	var contentType string
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == "content-type" {
+			contentType = f.Value
+		}
+	})
+	_, _ = dec.Write(block)
+	return contentType
+}
+
+// replayConn returns the sniffed bytes before reading from the connection again
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// muxListener receives connections from connMux
+type muxListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+	err   error // returned by Accept once done, net.ErrClosed when nil
+}
+
+func newMuxListener(addr net.Addr) *muxListener {
+	return &muxListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *muxListener) push(c net.Conn) bool {
+	select {
+	case l.conns <- c:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		if l.err != nil {
+			return nil, l.err
+		}
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *muxListener) Close() error {
+	l.closeWith(nil)
+	return nil
+}
+
+// closeWith close the listener, Accept returns err from then on
+func (l *muxListener) closeWith(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.done)
+	})
+}
+
+func (l *muxListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,123 @@
+package graceful
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestServer_Serve_GRPCAndHTTP(t *testing.T) {
+	mlog.New(nil)
+	s := New()
+	s.Engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	s.GRPC = grpc.NewServer()
+	healthpb.RegisterHealthServer(s.GRPC, health.NewServer())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	// HTTP/1.1
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("http/1.1 => %s", body)
+	}
+
+	// REST over h2c prior knowledge
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err = h2c.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 || string(body) != "pong" {
+		t.Errorf("h2c => %s %s", resp.Proto, body)
+	}
+
+	// gRPC
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := healthpb.NewHealthClient(conn).Check(dialCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("grpc health => %s", res.Status)
+	}
+	_ = conn.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutdown did not finish")
+	}
+}
+
+// brokenListener fails every Accept with a permanent error
+type brokenListener struct {
+	net.Listener
+	err error
+}
+
+func (l *brokenListener) Accept() (net.Conn, error) {
+	return nil, l.err
+}
+
+func TestServer_Serve_AcceptError(t *testing.T) {
+	mlog.New(nil)
+	s := New()
+	s.GRPC = grpc.NewServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	broken := errors.New("accept: broken")
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(context.Background(), &brokenListener{Listener: ln, err: broken}) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, broken) {
+			t.Errorf("serve => %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("accept error not returned")
+	}
+}
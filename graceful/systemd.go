@@ -0,0 +1,137 @@
+package graceful
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sd_notify states understood by systemd, see sd_notify(3).
+const (
+	SdReady     = "READY=1"
+	SdStopping  = "STOPPING=1"
+	SdReloading = "RELOADING=1"
+	SdWatchdog  = "WATCHDOG=1"
+)
+
+// sdListenFdsStart first file descriptor passed by systemd socket activation
+const sdListenFdsStart = 3
+
+// SdListeners
+/**
+ * @Description: returns the listeners passed by systemd through LISTEN_FDS, keyed in order.
+ * Returns nil when the process was not socket activated. When unsetEnv is true the
+ * LISTEN_* variables are removed so that child processes do not inherit them.
+ * @param unsetEnv
+ * @return []net.Listener
+ * @return []string fd names from LISTEN_FDNAMES, same order as listeners
+ * @return error
+ */
+func SdListeners(unsetEnv bool) ([]net.Listener, []string, error) {
+	if unsetEnv {
+		defer func() {
+			_ = os.Unsetenv("LISTEN_PID")
+			_ = os.Unsetenv("LISTEN_FDS")
+			_ = os.Unsetenv("LISTEN_FDNAMES")
+		}()
+	}
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds <= 0 {
+		return nil, nil, nil
+	}
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+	return sdListenersFrom(sdListenFdsStart, nfds, names)
+}
+
+// sdListenersFrom wraps nfds descriptors starting at start into listeners
+func sdListenersFrom(start, nfds int, names []string) ([]net.Listener, []string, error) {
+	listeners := make([]net.Listener, 0, nfds)
+	fdNames := make([]string, 0, nfds)
+	for fd := start; fd < start+nfds; fd++ {
+		closeOnExec(fd)
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i := fd - start; i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		// FileListener dups the descriptor, the original is no longer needed
+		_ = f.Close()
+		if err != nil {
+			for _, ln := range listeners {
+				_ = ln.Close()
+			}
+			return nil, nil, err
+		}
+		listeners = append(listeners, l)
+		fdNames = append(fdNames, name)
+	}
+	return listeners, fdNames, nil
+}
+
+// SdNotify
+/**
+ * @Description: sends state to the service manager through NOTIFY_SOCKET.
+ * Returns false without error when NOTIFY_SOCKET is not set.
+ * @param state e.g. SdReady
+ * @return bool sent
+ * @return error
+ */
+func SdNotify(state string) (bool, error) {
+	socketAddr := os.Getenv("NOTIFY_SOCKET")
+	if socketAddr == "" {
+		return false, nil
+	}
+	addr := &net.UnixAddr{Name: socketAddr, Net: "unixgram"}
+	// abstract namespace socket
+	if strings.HasPrefix(socketAddr, "@") {
+		addr.Name = "\x00" + socketAddr[1:]
+	}
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SdWatchdogEnabled
+/**
+ * @Description: returns the watchdog interval configured through WATCHDOG_USEC,
+ * zero when the watchdog is disabled or addressed to another process.
+ * @return time.Duration
+ * @return error
+ */
+func SdWatchdogEnabled() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, errors.New("invalid WATCHDOG_USEC " + usecStr)
+	}
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, errors.New("invalid WATCHDOG_PID " + pidStr)
+		}
+		if pid != os.Getpid() {
+			return 0, nil
+		}
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
@@ -0,0 +1,153 @@
+//go:build !windows
+// +build !windows
+
+package graceful
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+)
+
+// setenv set an environment variable for the duration of the test
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+// fakeNotifySocket listen a unixgram socket and point NOTIFY_SOCKET to it
+func fakeNotifySocket(t *testing.T) *net.UnixConn {
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "notify.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	setenv(t, "NOTIFY_SOCKET", addr.Name)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 256)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSdNotify(t *testing.T) {
+	conn := fakeNotifySocket(t)
+	sent, err := SdNotify(SdReady)
+	if err != nil || !sent {
+		t.Fatalf("SdNotify => %v %v", sent, err)
+	}
+	if v := readNotify(t, conn); v != SdReady {
+		t.Errorf("SdNotify => %s!=%s", v, SdReady)
+	}
+}
+
+func TestSdNotify_NoSocket(t *testing.T) {
+	setenv(t, "NOTIFY_SOCKET", "")
+	sent, err := SdNotify(SdReady)
+	if err != nil || sent {
+		t.Errorf("SdNotify without socket => %v %v", sent, err)
+	}
+}
+
+func TestSdListeners_NotActivated(t *testing.T) {
+	setenv(t, "LISTEN_PID", "1")
+	setenv(t, "LISTEN_FDS", "1")
+	listeners, _, err := SdListeners(false)
+	if err != nil || listeners != nil {
+		t.Errorf("SdListeners other pid => %v %v", listeners, err)
+	}
+}
+
+func TestSdListenersFrom(t *testing.T) {
+	origin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origin.Close()
+	f, err := origin.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listeners, names, err := sdListenersFrom(int(f.Fd()), 1, []string{"web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listeners[0].Close()
+	if names[0] != "web" {
+		t.Errorf("name => %s!=web", names[0])
+	}
+	if listeners[0].Addr().String() != origin.Addr().String() {
+		t.Errorf("addr => %s!=%s", listeners[0].Addr(), origin.Addr())
+	}
+	go func() { _ = http.Serve(listeners[0], http.NotFoundHandler()) }()
+	resp, err := http.Get("http://" + origin.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestSdWatchdogEnabled(t *testing.T) {
+	setenv(t, "WATCHDOG_USEC", "2000000")
+	setenv(t, "WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	interval, err := SdWatchdogEnabled()
+	if err != nil || interval != 2*time.Second {
+		t.Errorf("SdWatchdogEnabled => %s %v", interval, err)
+	}
+	setenv(t, "WATCHDOG_PID", "1")
+	if interval, _ = SdWatchdogEnabled(); interval != 0 {
+		t.Errorf("SdWatchdogEnabled other pid => %s", interval)
+	}
+}
+
+func TestServer_Run_Notify(t *testing.T) {
+	mlog.New(nil)
+	conn := fakeNotifySocket(t)
+	setenv(t, "WATCHDOG_USEC", "100000")
+	setenv(t, "WATCHDOG_PID", "")
+
+	s := New()
+	s.Ip = "127.0.0.1"
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	if v := readNotify(t, conn); v != SdReady {
+		t.Fatalf("first notify => %s!=%s", v, SdReady)
+	}
+	if v := readNotify(t, conn); v != SdWatchdog {
+		t.Fatalf("watchdog notify => %s!=%s", v, SdWatchdog)
+	}
+	cancel()
+	for {
+		if v := readNotify(t, conn); v == SdStopping {
+			break
+		}
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
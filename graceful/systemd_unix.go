@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package graceful
+
+import "syscall"
+
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
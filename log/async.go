@@ -0,0 +1,246 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Overflow policies of AsyncConfig.Overflow
+const (
+	OverflowBlock          = "block"            // wait for room, nothing is lost
+	OverflowDropNewest     = "drop-newest"      // drop the entry being written
+	OverflowDropDebugFirst = "drop-debug-first" // drop debug entries, queued or new, block for others
+)
+
+const (
+	defaultAsyncQueueSize     = 4096
+	defaultAsyncFlushInterval = time.Second
+	asyncBufferSize           = 256 * 1024
+)
+
+var errAsyncClosed = errors.New("log: async writer closed")
+
+// AsyncConfig
+/**
+ * @Description: serializes async writer related config in toml/json/yaml.
+ */
+type AsyncConfig struct {
+	Enable        bool          `toml:"enable" json:"enable" yaml:"enable"`
+	QueueSize     int           `toml:"queueSize" json:"queueSize" yaml:"queueSize"`             // Max queued entries, default 4096.
+	FlushInterval time.Duration `toml:"flushInterval" json:"flushInterval" yaml:"flushInterval"` // Buffered bytes are flushed at least this often, default 1s.
+	Overflow      string        `toml:"overflow" json:"overflow" yaml:"overflow"`                // block, drop-newest or drop-debug-first. Default block.
+}
+
+type asyncEntry struct {
+	lvl zapcore.Level
+	p   []byte
+}
+
+// AsyncWriter
+/**
+ * @Description: moves writes off the caller goroutine through a bounded queue.
+ * A background worker writes through a buffer flushed every FlushInterval;
+ * Sync drains the queue, flushes and syncs the underlying writer.
+ */
+type AsyncWriter struct {
+	out      zapcore.WriteSyncer
+	buf      *bufio.Writer
+	max      int
+	overflow string
+	interval time.Duration
+	dropped  uint64
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queue    []asyncEntry
+	syncs    []chan error
+	flushReq bool
+	closed   bool
+	done     chan struct{}
+}
+
+// NewAsyncWriter
+/**
+ * @Description: wrap out, the worker runs until Close
+ * @param out
+ * @param cfg
+ * @return *AsyncWriter
+ */
+func NewAsyncWriter(out zapcore.WriteSyncer, cfg AsyncConfig) *AsyncWriter {
+	w := &AsyncWriter{
+		out:      out,
+		buf:      bufio.NewWriterSize(out, asyncBufferSize),
+		max:      cfg.QueueSize,
+		overflow: cfg.Overflow,
+		interval: cfg.FlushInterval,
+		done:     make(chan struct{}),
+	}
+	if w.max <= 0 {
+		w.max = defaultAsyncQueueSize
+	}
+	if w.interval <= 0 {
+		w.interval = defaultAsyncFlushInterval
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+	go w.tick()
+	return w
+}
+
+// Write implements io.Writer, entries written without level count as info
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zapcore.InfoLevel, p)
+}
+
+// WriteLevel implements LevelWriter
+func (w *AsyncWriter) WriteLevel(lvl zapcore.Level, p []byte) (int, error) {
+	// the encoder reuses its buffer
+	entry := asyncEntry{lvl: lvl, p: append([]byte(nil), p...)}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.closed && len(w.queue) >= w.max {
+		switch w.overflow {
+		case OverflowDropNewest:
+			atomic.AddUint64(&w.dropped, 1)
+			return len(p), nil
+		case OverflowDropDebugFirst:
+			if lvl <= zapcore.DebugLevel {
+				atomic.AddUint64(&w.dropped, 1)
+				return len(p), nil
+			}
+			if w.dropQueuedDebug() {
+				continue
+			}
+		}
+		w.notFull.Wait()
+	}
+	if w.closed {
+		return 0, errAsyncClosed
+	}
+	w.queue = append(w.queue, entry)
+	w.notEmpty.Signal()
+	return len(p), nil
+}
+
+// dropQueuedDebug remove the oldest queued debug entry, caller holds mu
+func (w *AsyncWriter) dropQueuedDebug() bool {
+	for i, e := range w.queue {
+		if e.lvl <= zapcore.DebugLevel {
+			copy(w.queue[i:], w.queue[i+1:])
+			w.queue[len(w.queue)-1] = asyncEntry{}
+			w.queue = w.queue[:len(w.queue)-1]
+			atomic.AddUint64(&w.dropped, 1)
+			return true
+		}
+	}
+	return false
+}
+
+// Sync implements zapcore.WriteSyncer, returns once everything queued before it is written
+func (w *AsyncWriter) Sync() error {
+	ch := make(chan error, 1)
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.out.Sync()
+	}
+	w.syncs = append(w.syncs, ch)
+	w.notEmpty.Signal()
+	w.mu.Unlock()
+	return <-ch
+}
+
+// Close drain the queue, flush and stop the worker
+func (w *AsyncWriter) Close() error {
+	err := w.Sync()
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		w.notEmpty.Broadcast()
+		w.notFull.Broadcast()
+	}
+	w.mu.Unlock()
+	<-w.done
+	return err
+}
+
+// Dropped number of entries dropped by the overflow policy
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Len number of queued entries
+func (w *AsyncWriter) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.queue)
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && len(w.syncs) == 0 && !w.flushReq && !w.closed {
+			w.notEmpty.Wait()
+		}
+		batch := w.queue
+		syncs := w.syncs
+		flushReq := w.flushReq
+		closed := w.closed
+		w.queue, w.syncs, w.flushReq = nil, nil, false
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		for _, e := range batch {
+			// write errors have nowhere to go, the entry is lost like a failed sync write
+			_, _ = w.buf.Write(e.p)
+		}
+		if len(syncs) > 0 || closed {
+			err := w.flush()
+			if syncErr := w.out.Sync(); err == nil {
+				err = syncErr
+			}
+			for _, ch := range syncs {
+				ch <- err
+			}
+		} else if flushReq {
+			_ = w.flush()
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+// flush buffered bytes, called by the worker only
+func (w *AsyncWriter) flush() error {
+	return w.buf.Flush()
+}
+
+// tick request a periodic flush through the worker
+func (w *AsyncWriter) tick() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.closed {
+				w.mu.Unlock()
+				return
+			}
+			w.flushReq = true
+			w.notEmpty.Signal()
+			w.mu.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// gateWriter blocks writes until opened
+type gateWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	gate chan struct{}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gateWriter) Sync() error { return nil }
+
+func (g *gateWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.String()
+}
+
+// stall keep the worker busy in a flush until the gate opens
+func stall(w *AsyncWriter) {
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("stall\n"))
+	go func() { _ = w.Sync() }()
+	for i := 0; i < 100 && w.Len() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestAsyncWriter_Block(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	close(out.gate)
+	w := NewAsyncWriter(out, AsyncConfig{QueueSize: 2})
+	for i := 0; i < 100; i++ {
+		_, _ = w.Write([]byte("x\n"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "x\n"); n != 100 || w.Dropped() != 0 {
+		t.Errorf("written %d dropped %d", n, w.Dropped())
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("write after close accepted")
+	}
+}
+
+func TestAsyncWriter_DropNewest(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(out, AsyncConfig{QueueSize: 2, Overflow: OverflowDropNewest})
+	stall(w)
+	for i := 0; i < 5; i++ {
+		_, _ = w.WriteLevel(zapcore.ErrorLevel, []byte("e\n"))
+	}
+	if w.Dropped() != 3 {
+		t.Errorf("dropped => %d!=3", w.Dropped())
+	}
+	close(out.gate)
+	_ = w.Close()
+	if n := strings.Count(out.String(), "e\n"); n != 2 {
+		t.Errorf("written => %d!=2", n)
+	}
+}
+
+func TestAsyncWriter_DropDebugFirst(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(out, AsyncConfig{QueueSize: 2, Overflow: OverflowDropDebugFirst})
+	stall(w)
+	_, _ = w.WriteLevel(zapcore.DebugLevel, []byte("debug1\n"))
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("info1\n"))
+	_, _ = w.WriteLevel(zapcore.DebugLevel, []byte("debug2\n")) // dropped, queue full
+	_, _ = w.WriteLevel(zapcore.ErrorLevel, []byte("error1\n")) // evicts debug1
+	if w.Dropped() != 2 {
+		t.Errorf("dropped => %d!=2", w.Dropped())
+	}
+	close(out.gate)
+	_ = w.Close()
+	got := out.String()
+	if strings.Contains(got, "debug") || !strings.Contains(got, "info1\nerror1\n") {
+		t.Errorf("written => %q", got)
+	}
+}
+
+func TestInitLogger_Async(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "error", Format: "json", Async: AsyncConfig{Enable: true, FlushInterval: time.Hour}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	Info("queued")
+	_ = Sync()
+	if !strings.Contains(buf.String(), "queued") {
+		t.Errorf("Sync did not flush: %q", buf.String())
+	}
+	if Dropped() != 0 {
+		t.Errorf("Dropped => %d", Dropped())
+	}
+	_ = p.Syncer.(*AsyncWriter).Close()
+}
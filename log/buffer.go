@@ -0,0 +1,243 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultBufferMaxEntries = 1000
+
+// RequestBufferConfig
+/**
+ * @Description: serializes per-request buffering config in toml/json/yaml.
+ * Entries below the sink level but at or above Level, logged with the request
+ * id of a GinLogger request, are held until the request ends. A 5xx status, a
+ * panic or an error level entry of the request writes them out, otherwise they
+ * are discarded.
+ */
+type RequestBufferConfig struct {
+	Enable     bool   `toml:"enable" json:"enable" yaml:"enable"`             // Buffer low level entries per request.
+	Level      string `toml:"level" json:"level" yaml:"level"`                // Lowest level buffered, default debug.
+	MaxEntries int    `toml:"maxEntries" json:"maxEntries" yaml:"maxEntries"` // Per request, the oldest are dropped beyond, default 1000.
+}
+
+// Validate
+/**
+ * @Description: check level and maxEntries
+ * @receiver c
+ * @return error
+ */
+func (c *RequestBufferConfig) Validate() error {
+	if c.Level != "" {
+		if err := new(Level).Unpack(c.Level); err != nil {
+			return fmt.Errorf("log.requestBuffer.level: %w", err)
+		}
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("log.requestBuffer: maxEntries must not be negative")
+	}
+	return nil
+}
+
+// bufferedEntry entry held for a request, core carries the With fields it was logged with
+type bufferedEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// requestBuffer entries of one request id, shared by concurrent requests reusing it
+type requestBuffer struct {
+	mu      sync.Mutex
+	refs    int
+	entries []bufferedEntry
+	failed  bool // flushed, later entries are written straight away
+}
+
+// add hold e, or write it when the request already failed
+func (b *requestBuffer) add(e bufferedEntry, max int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed {
+		return e.core.Write(e.ent, e.fields)
+	}
+	if len(b.entries) >= max {
+		// keep the entries closest to the failure
+		b.entries[0] = bufferedEntry{}
+		b.entries = b.entries[1:]
+	}
+	b.entries = append(b.entries, e)
+	return nil
+}
+
+// flush write the held entries in order and switch to writing through
+func (b *requestBuffer) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var first error
+	for _, e := range b.entries {
+		if err := e.core.Write(e.ent, e.fields); err != nil && first == nil {
+			first = err
+		}
+	}
+	b.entries = nil
+	b.failed = true
+	return first
+}
+
+// requestBuffers
+/**
+ * @Description: buffers of the requests in flight, keyed by RequestIdKey
+ */
+type requestBuffers struct {
+	level  zapcore.Level
+	max    int
+	active int32 // open buffers, the core only looks up entries while > 0
+
+	mu      sync.Mutex
+	buffers map[string]*requestBuffer
+}
+
+func newRequestBuffers(cfg RequestBufferConfig) *requestBuffers {
+	b := &requestBuffers{level: zapcore.DebugLevel, max: cfg.MaxEntries, buffers: make(map[string]*requestBuffer)}
+	if cfg.Level != "" {
+		l := new(Level)
+		_ = l.Unpack(cfg.Level)
+		b.level = l.zapLevel()
+	}
+	if b.max <= 0 {
+		b.max = defaultBufferMaxEntries
+	}
+	return b
+}
+
+// open start buffering for rid
+func (r *requestBuffers) open(rid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buffers[rid]
+	if !ok {
+		b = &requestBuffer{}
+		r.buffers[rid] = b
+		atomic.AddInt32(&r.active, 1)
+	}
+	b.refs++
+}
+
+// close end a request of rid, failed writes what it buffered
+func (r *requestBuffers) close(rid string, failed bool) {
+	r.mu.Lock()
+	b, ok := r.buffers[rid]
+	if ok {
+		b.refs--
+		if b.refs == 0 {
+			delete(r.buffers, rid)
+			atomic.AddInt32(&r.active, -1)
+		}
+	}
+	r.mu.Unlock()
+	if ok && failed {
+		_ = b.flush()
+	}
+}
+
+func (r *requestBuffers) get(rid string) *requestBuffer {
+	if rid == "" || atomic.LoadInt32(&r.active) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buffers[rid]
+}
+
+// tailCore
+/**
+ * @Description: routes entries the wrapped core would skip to the buffer of
+ * their request. Only cores bound to a request id with an open buffer, through
+ * a With sessionId field or requestLogger, take such entries, so loggers
+ * outside a buffered request stay disabled below their level. Flushed
+ * entries go to flush, the sinks following Config.Level.
+ */
+type tailCore struct {
+	zapcore.Core
+	flush   zapcore.Core
+	buffers *requestBuffers
+	rid     string // from With fields
+}
+
+func newTailCore(core, flush zapcore.Core, buffers *requestBuffers) zapcore.Core {
+	return &tailCore{Core: core, flush: flush, buffers: buffers}
+}
+
+func (c *tailCore) buffering(lvl zapcore.Level) bool {
+	return lvl >= c.buffers.level && c.buffers.get(c.rid) != nil
+}
+
+func (c *tailCore) Enabled(lvl zapcore.Level) bool {
+	return c.Core.Enabled(lvl) || c.buffering(lvl)
+}
+
+func (c *tailCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &tailCore{Core: c.Core.With(fields), flush: c.flush.With(fields), buffers: c.buffers, rid: c.rid}
+	if rid := requestID(fields); rid != "" {
+		clone.rid = rid
+	}
+	return clone
+}
+
+func (c *tailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Enabled(ent.Level) {
+		if ent.Level >= zapcore.ErrorLevel && atomic.LoadInt32(&c.buffers.active) > 0 {
+			// flush the request context ahead of the error itself
+			ce = ce.AddCore(ent, c)
+		}
+		return c.Core.Check(ent, ce)
+	}
+	if c.buffering(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *tailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	rid := requestID(fields)
+	if rid == "" {
+		rid = c.rid
+	}
+	b := c.buffers.get(rid)
+	if b == nil {
+		return nil
+	}
+	if ent.Level >= zapcore.ErrorLevel {
+		return b.flush()
+	}
+	return b.add(bufferedEntry{core: c.flush, ent: ent, fields: append([]zapcore.Field(nil), fields...)}, c.buffers.max)
+}
+
+// requestLogger base bound to the buffer of rid, nil when rid is not buffered
+func (p *ZapProperties) requestLogger(base *zap.Logger, rid string) *zap.Logger {
+	if p.buffers == nil || p.buffers.get(rid) == nil {
+		return nil
+	}
+	tc, ok := p.Core.(*tailCore)
+	if !ok {
+		return nil
+	}
+	bound := *tc
+	bound.rid = rid
+	return base.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return &bound }))
+}
+
+// requestID value of the sessionId field
+func requestID(fields []zapcore.Field) string {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == SessionId.ToString() && fields[i].Type == zapcore.StringType {
+			return fields[i].String
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+func bufferRouter(t *testing.T) (*gin.Engine, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", RequestBuffer: RequestBufferConfig{Enable: true, MaxEntries: 2}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLogger(), GinRecovery(false))
+	r.GET("/ok", func(c *gin.Context) {
+		DebugCtx(c.Request.Context(), "ok detail")
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		DebugCtx(c.Request.Context(), "dropped by maxEntries")
+		DebugCtx(c.Request.Context(), "fail detail 1")
+		DebugCtx(c.Request.Context(), "fail detail 2")
+		c.Status(http.StatusBadGateway)
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		DebugCtx(c.Request.Context(), "panic detail")
+		panic("boom")
+	})
+	r.GET("/other", func(c *gin.Context) {
+		// loggers not bound to the request stay off below their level
+		if GetLogger().Core().Enabled(zapcore.DebugLevel) {
+			Error("debug enabled outside the buffered request")
+		}
+		Debug("not buffered")
+		c.Status(http.StatusBadGateway)
+	})
+	r.GET("/error", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		DebugCtx(ctx, "before error")
+		ErrorCtx(ctx, "query failed")
+		DebugCtx(ctx, "after error")
+		c.Status(http.StatusOK)
+	})
+	return r, buf
+}
+
+func serve(r *gin.Engine, path string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Request-ID", "rid"+strings.ReplaceAll(path, "/", "-"))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestRequestBuffer(t *testing.T) {
+	r, buf := bufferRouter(t)
+
+	serve(r, "/ok")
+	if out := buf.String(); strings.Contains(out, "ok detail") || !strings.Contains(out, `"msg":"/ok"`) {
+		t.Errorf("successful request => %s", out)
+	}
+	buf.Reset()
+
+	serve(r, "/fail")
+	out := buf.String()
+	if strings.Contains(out, "dropped by maxEntries") {
+		t.Errorf("maxEntries not applied => %s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "fail detail 1") || !strings.Contains(lines[1], "fail detail 2") {
+		t.Fatalf("5xx request => %s", out)
+	}
+	if !strings.Contains(lines[0], `"level":"DEBUG"`) || !strings.Contains(lines[0], `"sessionId":"rid-fail"`) {
+		t.Errorf("flushed entry => %s", lines[0])
+	}
+	buf.Reset()
+
+	serve(r, "/panic")
+	if out := buf.String(); !strings.Contains(out, "panic detail") || strings.Index(out, "panic detail") > strings.Index(out, "Recovery from panic") {
+		t.Errorf("panicking request => %s", out)
+	}
+	buf.Reset()
+
+	serve(r, "/other")
+	if out := buf.String(); strings.Contains(out, "debug enabled") || strings.Contains(out, "not buffered") {
+		t.Errorf("unbound logger => %s", out)
+	}
+	buf.Reset()
+
+	serve(r, "/error")
+	out = buf.String()
+	before, failed, after := strings.Index(out, "before error"), strings.Index(out, "query failed"), strings.Index(out, "after error")
+	if before < 0 || failed < before || after < failed {
+		t.Errorf("request logging an error => %s", out)
+	}
+	buf.Reset()
+
+	// no request in flight, debug stays off
+	Debug("outside a request")
+	if buf.Len() != 0 || GetLogger().Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("debug outside requests => %s", buf.String())
+	}
+}
+
+func TestRequestBufferConfig_Validate(t *testing.T) {
+	if err := (&Config{RequestBuffer: RequestBufferConfig{Level: "trace"}}).Validate(); err == nil {
+		t.Error("unknown level accepted")
+	}
+	if err := (&Config{RequestBuffer: RequestBufferConfig{MaxEntries: -1}}).Validate(); err == nil {
+		t.Error("negative maxEntries accepted")
+	}
+}
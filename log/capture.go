@@ -0,0 +1,281 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultCaptureMaxBytes = 4096
+
+var (
+	defaultCaptureContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/"}
+	defaultRedactHeaders       = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+)
+
+// BodyCapture
+/**
+ * @Description: GinLogger body capture settings, filling the HttpRequest and
+ * HttpResponse fields of the access log.
+ * Routes are gin route patterns such as /users/:id, empty captures every route.
+ * ContentTypes match by prefix, "text/" allows every text type.
+ * RedactFields are JSON keys masked at any depth and form fields, matched
+ * case insensitively.
+ */
+type BodyCapture struct {
+	MaxBytes      int      // Bodies are cut after this many bytes, default 4096.
+	ContentTypes  []string // Default application/json, application/x-www-form-urlencoded and text/.
+	Routes        []string
+	RedactFields  []string
+	RedactHeaders []string // Added to Authorization, Proxy-Authorization, Cookie and Set-Cookie.
+}
+
+// WithBodyCapture log request and response bodies as configured by bc
+func WithBodyCapture(bc BodyCapture) GinOption {
+	return ginOptionFunc(func(o *ginOptions) {
+		o.capture = newBodyCapturer(bc)
+	})
+}
+
+type bodyCapturer struct {
+	max          int
+	contentTypes []string
+	routes       map[string]bool
+	fields       map[string]bool
+	headers      map[string]bool
+	fieldRegex   *regexp.Regexp
+	formRegex    *regexp.Regexp
+}
+
+func newBodyCapturer(bc BodyCapture) *bodyCapturer {
+	b := &bodyCapturer{
+		max:          bc.MaxBytes,
+		contentTypes: bc.ContentTypes,
+		fields:       make(map[string]bool),
+		headers:      make(map[string]bool),
+	}
+	if b.max <= 0 {
+		b.max = defaultCaptureMaxBytes
+	}
+	if len(b.contentTypes) == 0 {
+		b.contentTypes = defaultCaptureContentTypes
+	}
+	if len(bc.Routes) > 0 {
+		b.routes = make(map[string]bool, len(bc.Routes))
+		for _, r := range bc.Routes {
+			b.routes[r] = true
+		}
+	}
+	for _, h := range append(append([]string(nil), defaultRedactHeaders...), bc.RedactHeaders...) {
+		b.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	names := make([]string, 0, len(bc.RedactFields))
+	for _, f := range bc.RedactFields {
+		b.fields[strings.ToLower(f)] = true
+		names = append(names, regexp.QuoteMeta(f))
+	}
+	if len(names) > 0 {
+		// fallback for bodies that are not valid JSON, such as truncated ones
+		b.fieldRegex = regexp.MustCompile(`(?i)("(?:` + strings.Join(names, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+		b.formRegex = regexp.MustCompile(`(?i)((?:^|[&;])(?:` + strings.Join(names, "|") + `)=)[^&;]*`)
+	}
+	return b
+}
+
+// enabled reports whether the route of c is captured
+func (b *bodyCapturer) enabled(c *gin.Context) bool {
+	if b.routes == nil {
+		return true
+	}
+	return b.routes[c.FullPath()]
+}
+
+func (b *bodyCapturer) allowed(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType := mediaTypeOf(contentType)
+	for _, ct := range b.contentTypes {
+		if strings.HasPrefix(mediaType, strings.ToLower(ct)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeOf lower case media type of a Content-Type header, without parameters
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	return strings.ToLower(mediaType)
+}
+
+// captureRequest read up to max bytes of the request body, the handler still
+// sees the complete body
+func (b *bodyCapturer) captureRequest(r *http.Request) *capturedBody {
+	cb := &capturedBody{header: b.redactHeader(r.Header)}
+	if r.Body == nil || r.Body == http.NoBody || !b.allowed(r.Header.Get("Content-Type")) {
+		return cb
+	}
+	peek, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(b.max)+1))
+	r.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(peek), r.Body), Closer: r.Body}
+	if err != nil {
+		return cb
+	}
+	if len(peek) > b.max {
+		peek, cb.truncated = peek[:b.max], true
+	}
+	cb.body = b.redactBody(r.Header.Get("Content-Type"), peek, cb.truncated)
+	cb.hasBody = true
+	return cb
+}
+
+// captureResponse summary of what the handler wrote through w
+func (b *bodyCapturer) captureResponse(w *captureWriter) *capturedBody {
+	cb := &capturedBody{header: b.redactHeader(w.Header())}
+	if !b.allowed(w.Header().Get("Content-Type")) || w.buf.Len() == 0 {
+		return cb
+	}
+	cb.body = b.redactBody(w.Header().Get("Content-Type"), w.buf.Bytes(), w.truncated)
+	cb.truncated = w.truncated
+	cb.hasBody = true
+	return cb
+}
+
+func (b *bodyCapturer) redactHeader(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if b.headers[http.CanonicalHeaderKey(k)] {
+			out[k] = redactedValue
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
+
+func (b *bodyCapturer) redactBody(contentType string, body []byte, truncated bool) string {
+	if len(b.fields) == 0 {
+		return string(body)
+	}
+	if mediaTypeOf(contentType) == "application/x-www-form-urlencoded" {
+		return b.redactForm(body)
+	}
+	if !truncated {
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err == nil {
+			if out, err := json.Marshal(b.redactValue(v)); err == nil {
+				return string(out)
+			}
+		}
+	}
+	return b.fieldRegex.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+}
+
+// redactForm mask form fields, re-encoded when the body parses. A truncated
+// body loses at most its last value, which is still masked when its name is complete.
+func (b *bodyCapturer) redactForm(body []byte) string {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return b.formRegex.ReplaceAllString(string(body), "${1}"+redactedValue)
+	}
+	for k := range values {
+		if b.fields[strings.ToLower(k)] {
+			values[k] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+func (b *bodyCapturer) redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if b.fields[strings.ToLower(k)] {
+				t[k] = redactedValue
+			} else {
+				t[k] = b.redactValue(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = b.redactValue(child)
+		}
+	}
+	return v
+}
+
+// capturedBody logged as an object under HttpRequest or HttpResponse
+type capturedBody struct {
+	header    map[string]string
+	body      string
+	hasBody   bool
+	truncated bool
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (cb *capturedBody) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	err := enc.AddObject("header", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		keys := make([]string, 0, len(cb.header))
+		for k := range cb.header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			enc.AddString(k, cb.header[k])
+		}
+		return nil
+	}))
+	if cb.hasBody {
+		enc.AddString("body", cb.body)
+	}
+	if cb.truncated {
+		enc.AddBool("truncated", true)
+	}
+	return err
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter keeps the first max bytes written to the response
+type captureWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.keep(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) keep(p []byte) {
+	room := w.max - w.buf.Len()
+	if len(p) > room {
+		p, w.truncated = p[:room], true
+	}
+	w.buf.Write(p)
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+func captureRouter(t *testing.T, bc BodyCapture) (*gin.Engine, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json"}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLogger(WithBodyCapture(bc)))
+	echo := func(c *gin.Context) {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, c.ContentType(), body)
+	}
+	r.POST("/login", echo)
+	r.POST("/upload", echo)
+	return r, buf
+}
+
+func accessLog(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	buf.Reset()
+	return m
+}
+
+func TestGinLogger_BodyCapture(t *testing.T) {
+	r, buf := captureRouter(t, BodyCapture{Routes: []string{"/login"}, RedactFields: []string{"password"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"bob","auth":{"Password":"s3cret"}}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("handler saw %q, body must reach it unchanged", rec.Body.String())
+	}
+
+	m := accessLog(t, buf)
+	reqLog, _ := m[HttpRequest.ToString()].(map[string]interface{})
+	respLog, _ := m[HttpResponse.ToString()].(map[string]interface{})
+	if reqLog == nil || respLog == nil {
+		t.Fatalf("bodies not captured: %v", m)
+	}
+	if body := reqLog["body"].(string); strings.Contains(body, "s3cret") || !strings.Contains(body, `"user":"bob"`) {
+		t.Errorf("request body => %s", body)
+	}
+	if respLog["body"] != reqLog["body"] {
+		t.Errorf("response body => %v", respLog["body"])
+	}
+	if h := reqLog["header"].(map[string]interface{}); h["Authorization"] != redactedValue {
+		t.Errorf("request header => %v", h)
+	}
+
+	// form bodies, parsed or not
+	for _, form := range []string{"user=bob&PASSWORD=s3cret", "user=bob&password=s3cret&bad=%zz"} {
+		req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		reqLog, _ = accessLog(t, buf)[HttpRequest.ToString()].(map[string]interface{})
+		if body, _ := reqLog["body"].(string); strings.Contains(body, "s3cret") || !strings.Contains(body, "user=bob") {
+			t.Errorf("form %q => %s", form, body)
+		}
+	}
+
+	// route not enabled
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if m := accessLog(t, buf); m[HttpRequest.ToString()] != nil {
+		t.Errorf("disabled route captured: %v", m)
+	}
+}
+
+func TestGinLogger_BodyCaptureLimits(t *testing.T) {
+	r, buf := captureRouter(t, BodyCapture{MaxBytes: 24, RedactFields: []string{"token"}})
+
+	payload := `{"token":"abcdef","data":"` + strings.Repeat("x", 100) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Body.String() != payload {
+		t.Fatalf("handler body cut => %d bytes", rec.Body.Len())
+	}
+	m := accessLog(t, buf)
+	reqLog := m[HttpRequest.ToString()].(map[string]interface{})
+	if reqLog["truncated"] != true || strings.Contains(reqLog["body"].(string), "abcdef") {
+		t.Errorf("truncated request => %v", reqLog)
+	}
+	if respLog := m[HttpResponse.ToString()].(map[string]interface{}); respLog["truncated"] != true {
+		t.Errorf("truncated response => %v", respLog)
+	}
+
+	// content type not allowed
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("binary"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	m = accessLog(t, buf)
+	if _, ok := m[HttpRequest.ToString()].(map[string]interface{})["body"]; ok {
+		t.Errorf("octet-stream body captured: %v", m)
+	}
+}
@@ -1,28 +1,45 @@
 package log
 
+import "fmt"
+
 // FileLogConfig
 /**
- * @Description: serializes file log related config in toml/json.
+ * @Description: serializes file log related config in toml/json/yaml.
  */
 type FileLogConfig struct {
-	FileDir    string `toml:"fileDir" json:"fileDir"`
-	FileName   string `toml:"fileName" json:"fileName"`     // Log filename, leave empty to disable file log.
-	MaxSize    int    `toml:"maxSize" json:"maxSize"`       // Max size for a single file, in MB.
-	MaxDays    int    `toml:"maxDays" json:"maxDays"`       // Max log keep days, default is never deleting.
-	MaxBackups int    `toml:"maxBackups" json:"maxBackups"` // Maximum number of old log files to retain.
-	Compress   bool   `toml:"compress" json:"compress"`     // Compress
+	FileDir    string `toml:"fileDir" json:"fileDir" yaml:"fileDir"`
+	FileName   string `toml:"fileName" json:"fileName" yaml:"fileName"`       // Log filename, leave empty to disable file log.
+	MaxSize    int    `toml:"maxSize" json:"maxSize" yaml:"maxSize"`          // Max size for a single file, in MB.
+	MaxDays    int    `toml:"maxDays" json:"maxDays" yaml:"maxDays"`          // Max log keep days, default is never deleting.
+	MaxBackups int    `toml:"maxBackups" json:"maxBackups" yaml:"maxBackups"` // Maximum number of old log files to retain.
+	Compress   bool   `toml:"compress" json:"compress" yaml:"compress"`       // Compress rotated files with gzip.
+
+	Rotation      string `toml:"rotation" json:"rotation" yaml:"rotation"`                // time, size or both. Default time, plus size when maxSize is set.
+	RotationHours int    `toml:"rotationHours" json:"rotationHours" yaml:"rotationHours"` // Time rotation period in hours, default 24.
+	MaxTotalSize  int    `toml:"maxTotalSize" json:"maxTotalSize" yaml:"maxTotalSize"`    // Cap on current and rotated files, in MB, oldest are deleted first.
 }
 
 // Config
 /**
- * @Description: serializes log related config in toml/json.
+ * @Description: serializes log related config in toml/json/yaml.
  */
 type Config struct {
-	CallSkip int           `toml:"callSkip" json:"callSkip"` // Log CallSkip
-	Level    string        `toml:"level" json:"level"`       // Log level.
-	StdLevel string        `toml:"stdLevel" json:"stdLevel"` // console level
-	Format   string        `toml:"format" json:"format"`     // Log format. one of json, text, or console.
-	File     FileLogConfig `toml:"file" json:"file"`         // File log config.
+	CallSkip int           `toml:"callSkip" json:"callSkip" yaml:"callSkip"` // Log CallSkip
+	Level    string        `toml:"level" json:"level" yaml:"level"`          // Log level.
+	StdLevel string        `toml:"stdLevel" json:"stdLevel" yaml:"stdLevel"` // console level
+	Format   string        `toml:"format" json:"format" yaml:"format"`       // Log format. one of console, json, logfmt, ecs or gelf.
+	File     FileLogConfig `toml:"file" json:"file" yaml:"file"`             // File log config.
+
+	Modules   map[string]string `toml:"modules" json:"modules" yaml:"modules"`       // Level per Named logger, e.g. billing: debug.
+	Async     AsyncConfig       `toml:"async" json:"async" yaml:"async"`             // Write the main output through a bounded queue.
+	Redact    RedactConfig      `toml:"redact" json:"redact" yaml:"redact"`          // Mask sensitive values in every output.
+	Sampling  SamplingConfig    `toml:"sampling" json:"sampling" yaml:"sampling"`    // Sample repeated messages.
+	RateLimit RateLimitConfig   `toml:"rateLimit" json:"rateLimit" yaml:"rateLimit"` // Cap entries per call site.
+	Sinks     []SinkConfig      `toml:"sinks" json:"sinks" yaml:"sinks"`             // Extra outputs; with sinks and no file name nothing goes to stdout but the console.
+
+	RequestBuffer RequestBufferConfig `toml:"requestBuffer" json:"requestBuffer" yaml:"requestBuffer"` // Keep debug entries of a request, written only when it fails.
+	Crash         CrashConfig         `toml:"crash" json:"crash" yaml:"crash"`                         // Crash report written by HandleCrash.
+	ExitCode      int                 `toml:"exitCode" json:"exitCode" yaml:"exitCode"`                // Exit code of Fatal, 1 to 255, 0 means the default 1 as Fatal never exits successfully.
 }
 
 // GetLevel
@@ -48,3 +65,86 @@ func (c *Config) GetStdLevel() *Level {
 	l.Unpack(c.StdLevel)
 	return l
 }
+
+// Validate
+/**
+ * @Description: check levels and format, empty values use defaults
+ * @receiver c
+ * @return error
+ */
+func (c *Config) Validate() error {
+	for name, v := range map[string]string{"level": c.Level, "stdLevel": c.StdLevel} {
+		if v == "" {
+			continue
+		}
+		if err := new(Level).Unpack(v); err != nil {
+			return fmt.Errorf("log.%s: %w", name, err)
+		}
+	}
+	for name, v := range c.Modules {
+		if err := new(Level).Unpack(v); err != nil {
+			return fmt.Errorf("log.modules.%s: %w", name, err)
+		}
+	}
+	switch c.Format {
+	case "", FormatConsole, FormatJSON, FormatLogfmt, FormatECS, FormatGELF:
+	default:
+		return fmt.Errorf("log.format: unknown format '%s'", c.Format)
+	}
+	switch c.Async.Overflow {
+	case "", OverflowBlock, OverflowDropNewest, OverflowDropDebugFirst:
+	default:
+		return fmt.Errorf("log.async.overflow: unknown policy '%s'", c.Async.Overflow)
+	}
+	if c.Async.QueueSize < 0 || c.Async.FlushInterval < 0 {
+		return fmt.Errorf("log.async: queueSize/flushInterval must not be negative")
+	}
+	if c.Redact.Enable {
+		if _, err := NewRedactor(c.Redact); err != nil {
+			return err
+		}
+	}
+	if err := c.Sampling.Validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return err
+	}
+	if err := c.RequestBuffer.Validate(); err != nil {
+		return err
+	}
+	if err := c.Crash.Validate(); err != nil {
+		return err
+	}
+	if c.ExitCode < 0 || c.ExitCode > 255 {
+		return fmt.Errorf("log.exitCode: must be between 1 and 255, 0 for the default")
+	}
+	for i := range c.Sinks {
+		if err := c.Sinks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return c.File.Validate()
+}
+
+// Validate
+/**
+ * @Description: check rotation settings
+ * @receiver c
+ * @return error
+ */
+func (c *FileLogConfig) Validate() error {
+	if c.MaxSize < 0 || c.MaxDays < 0 || c.MaxBackups < 0 || c.RotationHours < 0 || c.MaxTotalSize < 0 {
+		return fmt.Errorf("log.file: maxSize/maxDays/maxBackups/rotationHours/maxTotalSize must not be negative")
+	}
+	switch c.Rotation {
+	case "", RotateByTime:
+	case RotateBySize, RotateByBoth:
+		if c.MaxSize == 0 {
+			return fmt.Errorf("log.file: rotation '%s' needs maxSize", c.Rotation)
+		}
+	default:
+		return fmt.Errorf("log.file: unknown rotation '%s'", c.Rotation)
+	}
+	return nil
+}
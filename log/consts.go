@@ -12,6 +12,7 @@ const (
 	SessionId      ContextLogKey = "sessionId"
 	TraceId        ContextLogKey = "traceId"
 	SpanId         ContextLogKey = "spanId"
+	ParentSpanId   ContextLogKey = "parentSpanId"
 	ThreadId       ContextLogKey = "threadId"
 	Cluster        ContextLogKey = "cluster"
 	AppName        ContextLogKey = "appName"
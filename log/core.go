@@ -0,0 +1,69 @@
+package log
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelWriter
+/**
+ * @Description: a WriteSyncer that wants the level of each encoded entry,
+ * e.g. AsyncWriter dropping debug entries first when its queue is full
+ */
+type LevelWriter interface {
+	zapcore.WriteSyncer
+	WriteLevel(lvl zapcore.Level, p []byte) (int, error)
+}
+
+// sinkCore
+/**
+ * @Description: same as the zapcore io core, but hands the entry level to a LevelWriter
+ */
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out zapcore.WriteSyncer
+}
+
+func newSinkCore(enc zapcore.Encoder, out zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	return &sinkCore{LevelEnabler: enab, enc: enc, out: out}
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &sinkCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), out: c.out}
+	for i := range fields {
+		fields[i].AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	if lw, ok := c.out.(LevelWriter); ok {
+		_, err = lw.WriteLevel(ent.Level, buf.Bytes())
+	} else {
+		_, err = c.out.Write(buf.Bytes())
+	}
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// we may be crashing the program, sync the output
+		_ = c.Sync()
+	}
+	return nil
+}
+
+func (c *sinkCore) Sync() error {
+	return c.out.Sync()
+}
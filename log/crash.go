@@ -0,0 +1,194 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IvanWhisper/michelangelo/environment"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultCrashTailEntries = 200
+	maxCrashStackSize       = 64 * megabyte
+)
+
+// osExit ends the process after Fatal, replaced in tests
+var osExit = os.Exit
+
+// CrashConfig
+/**
+ * @Description: serializes crash report config in toml/json/yaml. When enabled
+ * the latest entries are kept in memory and HandleCrash writes them to a
+ * report file along with the stacks of all goroutines and the build info.
+ */
+type CrashConfig struct {
+	Enable      bool   `toml:"enable" json:"enable" yaml:"enable"`                // Write a report file from HandleCrash.
+	Dir         string `toml:"dir" json:"dir" yaml:"dir"`                         // Report directory, default file.fileDir, else the temp dir.
+	TailEntries int    `toml:"tailEntries" json:"tailEntries" yaml:"tailEntries"` // Recent entries in the report, default 200.
+}
+
+// Validate
+/**
+ * @Description: check tailEntries
+ * @receiver c
+ * @return error
+ */
+func (c *CrashConfig) Validate() error {
+	if c.TailEntries < 0 {
+		return fmt.Errorf("log.crash: tailEntries must not be negative")
+	}
+	return nil
+}
+
+// HandleCrash
+/**
+ * @Description: deferred at the top of main and of goroutines, e.g.
+ *  defer log.HandleCrash()
+ * On a panic it writes the crash report when Config.Crash is enabled, logs
+ * the panic, flushes every sink and panics again with the same value. Sinks
+ * stay open for whoever recovers further up, nested or outer HandleCrash
+ * calls only panic again.
+ */
+func HandleCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p := GetProps()
+	if !atomic.CompareAndSwapUint32(&p.crashed, 0, 1) {
+		panic(r)
+	}
+	fields := []zap.Field{zap.String("panic", fmt.Sprint(r)), zap.Stack("stacktrace")}
+	if p.crash != nil {
+		if path, err := p.crash.report(r); err != nil {
+			fields = append(fields, zap.NamedError("reportError", err))
+		} else {
+			fields = append(fields, zap.String("report", path))
+		}
+	}
+	GetLogger().Error("log: unrecovered panic", fields...)
+	_ = GetLogger().Sync()
+	panic(r)
+}
+
+// writeFatal write a fatal entry without zap exiting, exitFatal flushes the sinks first
+func writeFatal(l *zap.Logger, msg string, fields ...zap.Field) {
+	// zap exits with code 1 after a fatal entry unless told to panic instead
+	defer func() { _ = recover() }()
+	l.WithOptions(zap.OnFatal(zapcore.WriteThenPanic), zap.AddCallerSkip(1)).Fatal(msg, fields...)
+}
+
+// exitFatal flush and close every sink, then exit with Config.ExitCode
+func exitFatal() {
+	p := GetProps()
+	_ = GetLogger().Sync()
+	_ = p.Close()
+	code := p.exitCode
+	if code == 0 {
+		code = 1
+	}
+	osExit(code)
+}
+
+// crashReporter writes crash reports with the entries kept by tail
+type crashReporter struct {
+	dir  string
+	tail *tailRing
+}
+
+func newCrashReporter(cfg *Config) *crashReporter {
+	dir := cfg.Crash.Dir
+	if dir == "" {
+		dir = cfg.File.FileDir
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	n := cfg.Crash.TailEntries
+	if n == 0 {
+		n = defaultCrashTailEntries
+	}
+	return &crashReporter{dir: dir, tail: newTailRing(n)}
+}
+
+// report write a crash report for panic value r, returns its path
+func (c *crashReporter) report(r interface{}) (string, error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	path := filepath.Join(c.dir, fmt.Sprintf("crash-%s-%d.log", now.Format("20060102T150405.000"), os.Getpid()))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	build, _ := json.Marshal(environment.GetBuildInfo())
+	fmt.Fprintf(f, "panic: %v\ntime: %s\nbuild: %s\n\ngoroutines:\n%s\nrecent log entries:\n", r, now.Format(time.RFC3339Nano), build, allStacks())
+	for _, line := range c.tail.lines() {
+		_, _ = f.Write(line)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return path, err
+	}
+	return path, f.Close()
+}
+
+// allStacks stacks of all goroutines
+func allStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxCrashStackSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// tailRing sink output keeping the latest encoded entries
+type tailRing struct {
+	mu    sync.Mutex
+	ring  [][]byte
+	next  int
+	count int
+}
+
+func newTailRing(n int) *tailRing {
+	return &tailRing{ring: make([][]byte, n)}
+}
+
+func (t *tailRing) Write(p []byte) (int, error) {
+	line := append([]byte(nil), p...)
+	t.mu.Lock()
+	t.ring[t.next] = line
+	t.next = (t.next + 1) % len(t.ring)
+	if t.count < len(t.ring) {
+		t.count++
+	}
+	t.mu.Unlock()
+	return len(p), nil
+}
+
+func (t *tailRing) Sync() error {
+	return nil
+}
+
+// lines kept entries, oldest first
+func (t *tailRing) lines() [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([][]byte, 0, t.count)
+	for i := 0; i < t.count; i++ {
+		out = append(out, t.ring[(t.next-t.count+i+len(t.ring))%len(t.ring)])
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IvanWhisper/michelangelo/environment"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFatal_FlushesAndExits(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json", ExitCode: 3,
+		Async: AsyncConfig{Enable: true, FlushInterval: time.Hour}}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+	code, prevExit := -1, osExit
+	osExit = func(c int) { code = c }
+	t.Cleanup(func() { osExit = prevExit })
+
+	Info("before")
+	FatalCtx(context.WithValue(context.Background(), RequestIdKey, "r1"), "bye", zap.Int("n", 1))
+	if code != 3 {
+		t.Errorf("exit code => %d", code)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"before"`) || !strings.Contains(out, `"level":"FATAL"`) || !strings.Contains(out, `"msg":"bye"`) || !strings.Contains(out, `"n":1`) {
+		t.Errorf("output => %s", out)
+	}
+	if len(p.closers) != 0 {
+		t.Error("writers not closed")
+	}
+}
+
+func TestFatal_DefaultExitCode(t *testing.T) {
+	xormOutput(t)
+	code, prevExit := -1, osExit
+	osExit = func(c int) { code = c }
+	t.Cleanup(func() { osExit = prevExit })
+	Fatal("bye")
+	if code != 1 {
+		t.Errorf("exit code => %d", code)
+	}
+}
+
+func TestHandleCrash(t *testing.T) {
+	dir := t.TempDir()
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json",
+		Crash: CrashConfig{Enable: true, Dir: dir, TailEntries: 2}}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	Info("first")
+	Info("second")
+	Info("third")
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("repanic => %v", r)
+			}
+		}()
+		defer HandleCrash()
+		func() {
+			// nested, only the innermost writes a report
+			defer HandleCrash()
+			panic("boom")
+		}()
+	}()
+	Info("after")
+
+	reports, _ := filepath.Glob(filepath.Join(dir, "crash-*.log"))
+	if len(reports) != 1 {
+		t.Fatalf("reports => %v", reports)
+	}
+	data, err := ioutil.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{"panic: boom", `"version":"` + environment.GetBuildInfo().Version, "goroutine ", "TestHandleCrash", `"msg":"second"`, `"msg":"third"`} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, `"msg":"first"`) {
+		t.Error("tail keeps more than tailEntries")
+	}
+	if out := buf.String(); strings.Count(out, "log: unrecovered panic") != 1 || !strings.Contains(out, reports[0]) || !strings.Contains(out, `"msg":"after"`) {
+		t.Errorf("output => %s", out)
+	}
+}
+
+func TestTailRing(t *testing.T) {
+	r := newTailRing(3)
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		_, _ = r.Write([]byte(s))
+	}
+	var got []string
+	for _, line := range r.lines() {
+		got = append(got, string(line))
+	}
+	if strings.Join(got, "") != "cde" {
+		t.Errorf("lines => %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type debugCtxKey struct{}
+
+// debugOverride how GinLogger trusts the DebugRequestId header
+type debugOverride struct {
+	secret []byte
+}
+
+// WithDebugOverride
+/**
+ * @Description: a DebugRequestId header made by SignDebugRequestId raises
+ * the level of the request context to debug until it expires, other values
+ * just name the request as before. Panics on an empty secret.
+ * @param secret
+ * @return GinOption
+ */
+func WithDebugOverride(secret string) GinOption {
+	if secret == "" {
+		panic("log: WithDebugOverride needs a secret")
+	}
+	return ginOptionFunc(func(o *ginOptions) {
+		o.debug = &debugOverride{secret: []byte(secret)}
+	})
+}
+
+// SignDebugRequestId
+/**
+ * @Description: DebugRequestId header value for id valid for ttl,
+ * <id>.<unix expiry>.<hex HMAC-SHA256 of id|expiry>
+ * @param secret
+ * @param id
+ * @param ttl
+ * @return string
+ */
+func SignDebugRequestId(secret, id string, ttl time.Duration) string {
+	expiry := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return id + "." + expiry + "." + debugSignature([]byte(secret), id, expiry)
+}
+
+func debugSignature(secret []byte, id, expiry string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "|" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify request id of a header value, false when it may not raise the level
+func (d *debugOverride) verify(value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", false
+	}
+	rest, sig := value[:i], value[i+1:]
+	j := strings.LastIndexByte(rest, '.')
+	if j <= 0 {
+		return "", false
+	}
+	id, expiry := rest[:j], rest[j+1:]
+	if !hmac.Equal([]byte(sig), []byte(debugSignature(d.secret, id, expiry))) {
+		return "", false
+	}
+	if unix, err := strconv.ParseInt(expiry, 10, 64); err != nil || time.Now().Unix() > unix {
+		return "", false
+	}
+	return id, true
+}
+
+// ContextWithDebug
+/**
+ * @Description: entries logged with ctx, or a context derived from it, are
+ * emitted down to debug whatever the global level
+ * @param ctx
+ * @return context.Context
+ */
+func ContextWithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugCtxKey{}, true)
+}
+
+// DebugFromContext
+/**
+ * @Description: whether ctx was raised to debug by ContextWithDebug
+ * @param ctx
+ * @return bool
+ */
+func DebugFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	debug, _ := ctx.Value(debugCtxKey{}).(bool)
+	return debug
+}
+
+// debugCache debug logger derived from base
+type debugCache struct {
+	base   *zap.Logger
+	logger *zap.Logger
+}
+
+// debugLogger base with the sinks following Level opened to debug, built once per installed logger
+func (p *ZapProperties) debugLogger(base *zap.Logger) *zap.Logger {
+	if cached, ok := p.debug.Load().(*debugCache); ok && cached.base == base {
+		return cached.logger
+	}
+	core := p.newCore(func(enab zapcore.LevelEnabler) zapcore.LevelEnabler {
+		if enab == zapcore.LevelEnabler(p.AtomicLevel) {
+			return zapcore.DebugLevel
+		}
+		return enab
+	})
+	logger := base.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return core }))
+	p.debug.Store(&debugCache{base: base, logger: logger})
+	return logger
+}
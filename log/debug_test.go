@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+func debugRouter(t *testing.T, opts ...GinOption) (*gin.Engine, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json"}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLogger(opts...))
+	r.GET("/", func(c *gin.Context) {
+		DebugCtx(c.Request.Context(), "debug detail")
+		c.Status(http.StatusOK)
+	})
+	return r, buf
+}
+
+func debugRequest(r *gin.Engine, header string) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(string(DebugRequestId), header)
+	}
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGinLogger_DebugOverrideSigned(t *testing.T) {
+	r, buf := debugRouter(t, WithDebugOverride("s3cret"))
+
+	debugRequest(r, SignDebugRequestId("s3cret", "trace-me", time.Minute))
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"debug detail"`) || !strings.Contains(out, `"sessionId":"trace-me"`) {
+		t.Fatalf("signed header => %s", out)
+	}
+	buf.Reset()
+
+	expired := SignDebugRequestId("s3cret", "trace-me", -time.Minute)
+	forged := strings.Replace(SignDebugRequestId("s3cret", "trace-me", time.Minute), "trace-me", "other-id", 1)
+	for _, h := range []string{"", "trace-me", SignDebugRequestId("other", "trace-me", time.Minute), "trace-me.00", expired, forged} {
+		debugRequest(r, h)
+		if out := buf.String(); strings.Contains(out, "debug detail") {
+			t.Errorf("header %q raised the level => %s", h, out)
+		}
+		buf.Reset()
+	}
+}
+
+func TestGinLogger_DebugOverrideUnsigned(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("empty secret accepted")
+			}
+		}()
+		WithDebugOverride("")
+	}()
+
+	// without the option the header only names the request
+	r, buf := debugRouter(t)
+	debugRequest(r, "anyone")
+	if out := buf.String(); strings.Contains(out, "debug detail") || !strings.Contains(out, `"sessionId":"anyone"`) {
+		t.Errorf("override off => %s", out)
+	}
+}
+
+func TestContextWithDebug(t *testing.T) {
+	_, buf := debugRouter(t)
+	ctx := ContextWithDebug(context.Background())
+	DebugCtx(ctx, "raised")
+	Debug("global")
+	if out := buf.String(); !strings.Contains(out, "raised") || strings.Contains(out, "global") {
+		t.Errorf("output => %s", out)
+	}
+	if DebugFromContext(context.Background()) || DebugFromContext(nil) { //nolint:staticcheck
+		t.Error("plain context raised")
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsVersion Elastic Common Schema version the field names follow
+const ecsVersion = "1.6.0"
+
+// ecsFields ContextLogKey fields with an ECS equivalent, others keep their names
+var ecsFields = map[string]string{
+	SessionId.ToString():      "http.request.id",
+	TraceId.ToString():        "trace.id",
+	SpanId.ToString():         "span.id",
+	ParentSpanId.ToString():   "parent.id",
+	ThreadId.ToString():       "process.thread.id",
+	Cluster.ToString():        "orchestrator.cluster.name",
+	AppName.ToString():        "service.name",
+	Version.ToString():        "service.version",
+	ServerAddr.ToString():     "server.address",
+	ServerPort.ToString():     "server.port",
+	HttpPath.ToString():       "url.path",
+	HttpMethod.ToString():     "http.request.method",
+	QueryText.ToString():      "url.query",
+	ContentType.ToString():    "http.request.mime_type",
+	StatusCode.ToString():     "http.response.status_code",
+	RequestSize.ToString():    "http.request.body.bytes",
+	ClientIp.ToString():       "client.ip",
+	UserAgent.ToString():      "user_agent.original",
+	Duration.ToString():       "event.duration",
+	BusinessUserID.ToString(): "user.id",
+	"error":                   "error.message",
+}
+
+func ecsKey(key string) string {
+	if ecs, ok := ecsFields[key]; ok {
+		return ecs
+	}
+	return key
+}
+
+// ecsEncoder
+/**
+ * @Description: Elastic Common Schema JSON, durations in nanoseconds as ECS
+ * requires, the caller split into log.origin.file.name and line
+ */
+type ecsEncoder struct {
+	*renameEncoder
+}
+
+func newECSEncoder() zapcore.Encoder {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:        "@timestamp",
+		LevelKey:       "log.level",
+		NameKey:        "log.logger",
+		CallerKey:      zapcore.OmitKey,
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		StacktraceKey:  "error.stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	return &ecsEncoder{&renameEncoder{Encoder: zapcore.NewJSONEncoder(cfg), rename: ecsKey}}
+}
+
+func (e *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{&renameEncoder{Encoder: e.Encoder.Clone(), rename: e.rename}}
+}
+
+func (e *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	head := []zapcore.Field{zap.String("ecs.version", ecsVersion)}
+	if ent.Caller.Defined {
+		head = append(head, zap.String("log.origin.file.name", callerFile(ent.Caller)), zap.Int("log.origin.file.line", ent.Caller.Line))
+	}
+	return e.Encoder.EncodeEntry(ent, append(head, e.renameFields(ecsErrors(fields))...))
+}
+
+// ecsErrors the Errors field becomes error.message unless an error field
+// already takes that key, empty ones are left out
+func ecsErrors(fields []zapcore.Field) []zapcore.Field {
+	errorsAt, hasError := -1, false
+	for i, f := range fields {
+		switch f.Key {
+		case Errors.ToString():
+			errorsAt = i
+		case "error":
+			hasError = true
+		}
+	}
+	if errorsAt < 0 {
+		return fields
+	}
+	out := make([]zapcore.Field, 0, len(fields))
+	for i, f := range fields {
+		if i == errorsAt || f.Key == Errors.ToString() {
+			if f.Type == zapcore.StringType && f.String == "" {
+				continue
+			}
+			if i == errorsAt && !hasError {
+				f.Key = "error.message"
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// callerFile trimmed caller path without the line number
+func callerFile(c zapcore.EntryCaller) string {
+	path := c.TrimmedPath()
+	if i := strings.LastIndexByte(path, ':'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Formats understood by BuildEncoder, an empty format is console
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+	FormatECS     = "ecs"
+	FormatGELF    = "gelf"
+)
+
+// renameEncoder
+/**
+ * @Description: renames top level keys before they reach the wrapped encoder,
+ * nested object keys are left alone
+ */
+type renameEncoder struct {
+	zapcore.Encoder
+	rename func(key string) string
+}
+
+func (e *renameEncoder) renameFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		f.Key = e.rename(f.Key)
+		out[i] = f
+	}
+	return out
+}
+
+func (e *renameEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	return e.Encoder.AddArray(e.rename(key), m)
+}
+
+func (e *renameEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	return e.Encoder.AddObject(e.rename(key), m)
+}
+
+func (e *renameEncoder) AddBinary(key string, v []byte) {
+	e.Encoder.AddBinary(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddByteString(key string, v []byte) {
+	e.Encoder.AddByteString(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddBool(key string, v bool) {
+	e.Encoder.AddBool(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddComplex128(key string, v complex128) {
+	e.Encoder.AddComplex128(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddComplex64(key string, v complex64) {
+	e.Encoder.AddComplex64(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddDuration(key string, v time.Duration) {
+	e.Encoder.AddDuration(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddFloat64(key string, v float64) {
+	e.Encoder.AddFloat64(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddFloat32(key string, v float32) {
+	e.Encoder.AddFloat32(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddInt(key string, v int) {
+	e.Encoder.AddInt(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddInt64(key string, v int64) {
+	e.Encoder.AddInt64(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddInt32(key string, v int32) {
+	e.Encoder.AddInt32(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddInt16(key string, v int16) {
+	e.Encoder.AddInt16(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddInt8(key string, v int8) {
+	e.Encoder.AddInt8(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddString(key, v string) {
+	e.Encoder.AddString(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddTime(key string, v time.Time) {
+	e.Encoder.AddTime(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUint(key string, v uint) {
+	e.Encoder.AddUint(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUint64(key string, v uint64) {
+	e.Encoder.AddUint64(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUint32(key string, v uint32) {
+	e.Encoder.AddUint32(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUint16(key string, v uint16) {
+	e.Encoder.AddUint16(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUint8(key string, v uint8) {
+	e.Encoder.AddUint8(e.rename(key), v)
+}
+
+func (e *renameEncoder) AddUintptr(key string, v uintptr) {
+	e.Encoder.AddUintptr(e.rename(key), v)
+}
+
+func (e *renameEncoder) OpenNamespace(key string) {
+	e.Encoder.OpenNamespace(e.rename(key))
+}
+
+func (e *renameEncoder) AddReflected(key string, v interface{}) error {
+	return e.Encoder.AddReflected(e.rename(key), v)
+}
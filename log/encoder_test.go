@@ -0,0 +1,123 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func encode(t *testing.T, format string, with []zap.Field, fields ...zap.Field) string {
+	enc, err := BuildEncoder(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range with {
+		f.AddTo(enc)
+	}
+	ent := zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC),
+		Message: "slow request",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/handler.go", 42, true),
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestBuildEncoder_Unknown(t *testing.T) {
+	if _, err := BuildEncoder("xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+	if err := (&Config{Format: "xml"}).Validate(); err == nil {
+		t.Error("Validate accepted unknown format")
+	}
+	if _, _, err := InitLoggerWithWriteSyncer(&Config{Format: "xml"}, zapcore.AddSync(&strings.Builder{})); err == nil {
+		t.Error("InitLoggerWithWriteSyncer accepted unknown format")
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	got := encode(t, FormatLogfmt, []zap.Field{zap.String(AppName.ToString(), "api")},
+		zap.String(HttpPath.ToString(), "/users"),
+		zap.String("note", `say "hi"`),
+		zap.Duration(Duration.ToString(), 1500*time.Millisecond),
+		zap.Object("req", &capturedBody{header: map[string]string{"Accept": "*/*"}}),
+		zap.Strings("tags", []string{"a", "b"}),
+	)
+	want := `datetime=2021-05-01T10:00:00.000Z level=WARN caller=app/handler.go:42 msg="slow request" appName=api httpPath=/users note="say \"hi\"" duration=1.5 req.header.Accept=*/* tags="[\"a\",\"b\"]"` + "\n"
+	if got != want {
+		t.Errorf("logfmt =>\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestECSEncoder(t *testing.T) {
+	got := encode(t, FormatECS, []zap.Field{zap.String(TraceId.ToString(), "t1")},
+		zap.Int(StatusCode.ToString(), 503),
+		zap.Duration(Duration.ToString(), time.Millisecond),
+		zap.Error(errors.New("boom")),
+		zap.String("custom", "kept"),
+	)
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &m); err != nil {
+		t.Fatalf("%v: %s", err, got)
+	}
+	want := map[string]interface{}{
+		"@timestamp":                "2021-05-01T10:00:00.000Z",
+		"log.level":                 "warn",
+		"message":                   "slow request",
+		"ecs.version":               ecsVersion,
+		"log.origin.file.name":      "app/handler.go",
+		"log.origin.file.line":      float64(42),
+		"trace.id":                  "t1",
+		"http.response.status_code": float64(503),
+		"event.duration":            float64(time.Millisecond),
+		"error.message":             "boom",
+		"custom":                    "kept",
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s => %v!=%v", k, m[k], v)
+		}
+	}
+}
+
+func TestECSEncoder_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		fields []zap.Field
+		want   string
+	}{
+		{[]zap.Field{zap.String(Errors.ToString(), "sql failed")}, `"error.message":"sql failed"`},
+		{[]zap.Field{zap.String(Errors.ToString(), ""), zap.Error(errors.New("boom"))}, `"error.message":"boom"}`},
+		{[]zap.Field{zap.String(Errors.ToString(), "sql failed"), zap.Error(errors.New("boom"))}, `"errors":"sql failed","error.message":"boom"}`},
+	} {
+		got := encode(t, FormatECS, nil, tc.fields...)
+		if !strings.Contains(got, tc.want) || strings.Count(got, `"error.message"`) != 1 {
+			t.Errorf("%v => %s", tc.fields, got)
+		}
+	}
+}
+
+func TestGELFEncoder(t *testing.T) {
+	got := encode(t, FormatGELF, nil, zap.String("id", "x"), zap.String(SessionId.ToString(), "rid"))
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &m); err != nil {
+		t.Fatalf("%v: %s", err, got)
+	}
+	if m["version"] != gelfVersion || m["host"] == "" || m["short_message"] != "slow request" || m["level"] != float64(4) {
+		t.Errorf("gelf => %s", got)
+	}
+	if m["timestamp"] != float64(time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC).Unix()) {
+		t.Errorf("timestamp => %v", m["timestamp"])
+	}
+	if m["_sessionId"] != "rid" || m["_id_"] != "x" || m["_caller"] != "app/handler.go:42" {
+		t.Errorf("additional fields => %s", got)
+	}
+}
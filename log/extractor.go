@@ -0,0 +1,128 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// CtxExtractor
+/**
+ * @Description: returns the log fields a package stored in ctx, nil when absent
+ */
+type CtxExtractor func(ctx context.Context) []zap.Field
+
+type namedExtractor struct {
+	name string
+	fn   CtxExtractor
+}
+
+var (
+	_gExtractorMu sync.Mutex
+	_gExtractors  atomic.Value // []namedExtractor, copied on write
+)
+
+func init() {
+	_gExtractors.Store([]namedExtractor{})
+	RegisterCtxExtractor("requestId", func(ctx context.Context) []zap.Field {
+		rid, ok := ctx.Value(RequestIdKey).(string)
+		if !ok {
+			return nil
+		}
+		if _, traced := TraceFromContext(ctx); traced {
+			return []zap.Field{zap.String(SessionId.ToString(), rid)}
+		}
+		// no trace context, the request id stands in for the trace id
+		return []zap.Field{zap.String(SessionId.ToString(), rid), zap.String(TraceId.ToString(), rid)}
+	})
+	RegisterCtxExtractor("trace", func(ctx context.Context) []zap.Field {
+		tc, ok := TraceFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return []zap.Field{zap.String(TraceId.ToString(), tc.TraceID), zap.String(SpanId.ToString(), tc.SpanID)}
+	})
+	for _, key := range []ContextLogKey{BusinessKeyword, BusinessOperation, BusinessTitle, BusinessUserID} {
+		RegisterCtxExtractor(key.ToString(), StringExtractor(key, key.ToString()))
+	}
+}
+
+// RegisterCtxExtractor
+/**
+ * @Description: add an extractor run by Ctx2Fields for every log line,
+ * registering an existing name replaces it in place
+ * @param name
+ * @param fn
+ */
+func RegisterCtxExtractor(name string, fn CtxExtractor) {
+	_gExtractorMu.Lock()
+	defer _gExtractorMu.Unlock()
+	old := _gExtractors.Load().([]namedExtractor)
+	next := make([]namedExtractor, 0, len(old)+1)
+	replaced := false
+	for _, e := range old {
+		if e.name == name {
+			e.fn = fn
+			replaced = true
+		}
+		next = append(next, e)
+	}
+	if !replaced {
+		next = append(next, namedExtractor{name: name, fn: fn})
+	}
+	_gExtractors.Store(next)
+}
+
+// UnregisterCtxExtractor
+/**
+ * @Description: remove an extractor, including the built-in ones
+ * @param name
+ */
+func UnregisterCtxExtractor(name string) {
+	_gExtractorMu.Lock()
+	defer _gExtractorMu.Unlock()
+	old := _gExtractors.Load().([]namedExtractor)
+	next := make([]namedExtractor, 0, len(old))
+	for _, e := range old {
+		if e.name != name {
+			next = append(next, e)
+		}
+	}
+	_gExtractors.Store(next)
+}
+
+// StringExtractor
+/**
+ * @Description: logs ctx.Value(key) as field. Strings and fmt.Stringer are logged
+ * as strings, other values through zap.Any; a missing value logs nothing.
+ * @param key
+ * @param field
+ * @return CtxExtractor
+ */
+func StringExtractor(key interface{}, field string) CtxExtractor {
+	return func(ctx context.Context) []zap.Field {
+		switch v := ctx.Value(key).(type) {
+		case nil:
+			return nil
+		case string:
+			return []zap.Field{zap.String(field, v)}
+		case fmt.Stringer:
+			return []zap.Field{zap.Stringer(field, v)}
+		default:
+			return []zap.Field{zap.Any(field, v)}
+		}
+	}
+}
+
+// extract run one extractor, a panicking extractor only loses its own fields
+func extract(ctx context.Context, e namedExtractor) (fields []zap.Field) {
+	defer func() {
+		if r := recover(); r != nil {
+			fields = []zap.Field{zap.String(Errors.ToString(), fmt.Sprintf("ctx extractor %s: %v", e.name, r))}
+		}
+	}()
+	return e.fn(ctx)
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type tenantKey struct{}
+
+type userID int
+
+func (u userID) String() string { return fmt.Sprintf("u-%d", int(u)) }
+
+func fieldMap(fields []zap.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func TestCtx2Fields_Registry(t *testing.T) {
+	RegisterCtxExtractor("tenant", StringExtractor(tenantKey{}, "tenantId"))
+	RegisterCtxExtractor("user", StringExtractor(BusinessUserID, "user"))
+	defer UnregisterCtxExtractor("tenant")
+	defer UnregisterCtxExtractor("user")
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = context.WithValue(ctx, RequestIdKey, "rid-1")
+	ctx = context.WithValue(ctx, BusinessUserID, userID(7))
+	// a non-string value under a built-in key must not panic
+	ctx = context.WithValue(ctx, BusinessTitle, 42)
+
+	got := fieldMap(Ctx2Fields(ctx))
+	want := map[string]interface{}{
+		"tenantId":                "acme",
+		SessionId.ToString():      "rid-1",
+		TraceId.ToString():        "rid-1",
+		"user":                    "u-7",
+		BusinessUserID.ToString(): "u-7",
+		BusinessTitle.ToString():  int64(42),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s => %v!=%v", k, got[k], v)
+		}
+	}
+}
+
+func TestCtx2Fields_Safe(t *testing.T) {
+	RegisterCtxExtractor("broken", func(ctx context.Context) []zap.Field { panic("boom") })
+	defer UnregisterCtxExtractor("broken")
+
+	//nolint:staticcheck // nil ctx must be tolerated
+	if fields := Ctx2Fields(nil); len(fields) != 0 {
+		t.Errorf("nil ctx => %v", fields)
+	}
+	ctx := context.WithValue(context.Background(), RequestIdKey, 123)
+	got := fieldMap(Ctx2Fields(ctx))
+	if _, ok := got[SessionId.ToString()]; ok {
+		t.Errorf("non-string request id logged: %v", got)
+	}
+	if got[Errors.ToString()] == nil {
+		t.Errorf("panicking extractor not reported: %v", got)
+	}
+}
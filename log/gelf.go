@@ -0,0 +1,66 @@
+package log
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const gelfVersion = "1.1"
+
+// gelfEncoder
+/**
+ * @Description: Graylog Extended Log Format 1.1. Levels are syslog severities,
+ * the stack trace is the full_message and every other field is an additional
+ * field prefixed with an underscore.
+ */
+type gelfEncoder struct {
+	*renameEncoder
+	host string
+}
+
+func newGELFEncoder() zapcore.Encoder {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "_logger",
+		CallerKey:      "_caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "short_message",
+		StacktraceKey:  "full_message",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    gelfLevelEncoder,
+		EncodeTime:     zapcore.EpochTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return &gelfEncoder{renameEncoder: &renameEncoder{Encoder: zapcore.NewJSONEncoder(cfg), rename: gelfKey}, host: host}
+}
+
+func (e *gelfEncoder) Clone() zapcore.Encoder {
+	return &gelfEncoder{renameEncoder: &renameEncoder{Encoder: e.Encoder.Clone(), rename: e.rename}, host: e.host}
+}
+
+func (e *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	head := []zapcore.Field{zap.String("version", gelfVersion), zap.String("host", e.host)}
+	return e.Encoder.EncodeEntry(ent, append(head, e.renameFields(fields)...))
+}
+
+// gelfKey additional fields start with an underscore, _id is reserved
+func gelfKey(key string) string {
+	if key == "id" {
+		return "_id_"
+	}
+	return "_" + key
+}
+
+// gelfLevelEncoder syslog severity of lvl
+func gelfLevelEncoder(lvl zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt(syslogSeverity(lvl))
+}
@@ -16,22 +16,62 @@ import (
 	"time"
 )
 
+// GinOption configures GinLogger
+type GinOption interface {
+	apply(*ginOptions)
+}
+
+type ginOptionFunc func(*ginOptions)
+
+func (f ginOptionFunc) apply(o *ginOptions) {
+	f(o)
+}
+
+type ginOptions struct {
+	tracer  Tracer
+	capture *bodyCapturer
+	debug   *debugOverride
+}
+
+// WithTracer start a span per request through t, logs carry its trace and span ids
+func WithTracer(t Tracer) GinOption {
+	return ginOptionFunc(func(o *ginOptions) {
+		o.tracer = t
+	})
+}
+
 // GinLogger
 /**
- * @Description:
+ * @Description: access log middleware. Continues the W3C trace of the incoming
+ * traceparent header, or starts one, and stores it in the request context
+ * under TraceId/SpanId; the response carries the traceparent of this span.
+ * With Config.RequestBuffer the debug entries of the request are written only
+ * when it ends with a 5xx or a panic, or logs an error.
+ * WithDebugOverride lets the DebugRequestId header raise the request to debug.
+ * @param opts
  * @return gin.HandlerFunc
  */
-func GinLogger() gin.HandlerFunc {
+func GinLogger(opts ...GinOption) gin.HandlerFunc {
+	o := &ginOptions{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
 		rid := c.GetHeader("X-Request-ID")
+		debugLevel := false
 
 		// 用户链路调试
 		if debugID := c.GetHeader(string(DebugRequestId)); debugID != "" {
 			rid = debugID
+			if o.debug != nil {
+				if id, ok := o.debug.verify(debugID); ok {
+					rid, debugLevel = id, true
+				}
+			}
 		}
 
 		if rid == "" {
@@ -39,11 +79,55 @@ func GinLogger() gin.HandlerFunc {
 		}
 
 		var ridCtx = context.WithValue(c.Request.Context(), RequestIdKey, rid) // session id
-		c.Request = c.Request.WithContext(ridCtx)
-		c.Next()
+		if debugLevel {
+			ridCtx = ContextWithDebug(ridCtx)
+		}
+		tc := extractTrace(c.Request.Header)
+		if o.tracer != nil {
+			name := c.FullPath()
+			if name == "" {
+				name = path
+			}
+			var end func()
+			ridCtx, tc, end = o.tracer.Start(ridCtx, c.Request.Method+" "+name, tc)
+			defer end()
+		}
+		c.Request = c.Request.WithContext(ContextWithTrace(ridCtx, tc))
+		c.Header(TraceparentHeader, tc.Traceparent())
+		if tc.State != "" {
+			c.Header(TracestateHeader, tc.State)
+		}
+		var (
+			reqBody *capturedBody
+			cw      *captureWriter
+		)
+		if o.capture != nil && o.capture.enabled(c) {
+			reqBody = o.capture.captureRequest(c.Request)
+			cw = &captureWriter{ResponseWriter: c.Writer, max: o.capture.max}
+			c.Writer = cw
+		}
+		buffers := GetProps().buffers
+		if buffers != nil {
+			buffers.open(rid)
+			closed := false
+			defer func() {
+				// a panic unwinding through here, keep what led to it
+				if !closed {
+					buffers.close(rid, true)
+				}
+			}()
+			c.Next()
+			buffers.close(rid, c.Writer.Status() >= http.StatusInternalServerError)
+			closed = true
+		} else {
+			c.Next()
+		}
 		cost := time.Since(start)
-		GetLogger().Info(path,
+		fields := []zap.Field{
 			zap.String(SessionId.ToString(), rid),
+			zap.String(TraceId.ToString(), tc.TraceID),
+			zap.String(SpanId.ToString(), tc.SpanID),
+			zap.String(ParentSpanId.ToString(), tc.ParentID),
 			zap.Int(StatusCode.ToString(), c.Writer.Status()),
 			zap.String(HttpMethod.ToString(), c.Request.Method),
 			zap.String(HttpPath.ToString(), path),
@@ -52,7 +136,14 @@ func GinLogger() gin.HandlerFunc {
 			zap.String(UserAgent.ToString(), c.Request.UserAgent()),
 			zap.String(Errors.ToString(), c.Errors.ByType(gin.ErrorTypePrivate).String()),
 			zap.Duration(Duration.ToString(), cost),
-		)
+		}
+		if reqBody != nil {
+			fields = append(fields,
+				zap.Object(HttpRequest.ToString(), reqBody),
+				zap.Object(HttpResponse.ToString(), o.capture.captureResponse(cw)),
+			)
+		}
+		GetLogger().Info(path, fields...)
 	}
 }
 
@@ -80,8 +171,10 @@ func GinRecovery(stack bool) gin.HandlerFunc {
 				}
 
 				httpRequest, _ := httputil.DumpRequest(c.Request, false)
+				// request fields, the error also writes the buffered entries of the request
+				logger := GetLogger().With(Ctx2Fields(c.Request.Context())...)
 				if brokenPipe {
-					GetLogger().Sugar().Error(c.Request.URL.Path, zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)))
+					logger.Sugar().Error(c.Request.URL.Path, zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)))
 					// If the connection is dead, we can't write a status to it.
 					c.Error(err.(error)) // nolint: error check
 					c.Abort()
@@ -89,9 +182,9 @@ func GinRecovery(stack bool) gin.HandlerFunc {
 				}
 
 				if stack {
-					GetLogger().Sugar().Error("Recovery from panic", zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)), zap.String("stack", string(debug.Stack())))
+					logger.Sugar().Error("Recovery from panic", zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)), zap.String("stack", string(debug.Stack())))
 				} else {
-					GetLogger().Sugar().Error("Recovery from panic", zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)))
+					logger.Sugar().Error("Recovery from panic", zap.Any(Errors.ToString(), err), zap.String(HttpRequest.ToString(), string(httpRequest)))
 				}
 				c.AbortWithStatus(http.StatusInternalServerError)
 			}
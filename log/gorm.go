@@ -0,0 +1,147 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	glogger "gorm.io/gorm/logger"
+)
+
+// _gGormLogger output of GormLogger, named gorm so its level can be set through Config.Modules
+var _gGormLogger = Named("gorm").WithOptions(zap.AddCallerSkip(2))
+
+// GormLogger
+/**
+ * @Description: gorm logger.Interface writing statements with the fields of
+ * OrmLoggerAdapter.AfterSQL. gorm hands over statements with their values
+ * already in place, so MaskColumns and MaskArgs do not apply.
+ * The zero value is silent until LogMode.
+ */
+type GormLogger struct {
+	level glogger.LogLevel
+	sql   *sqlLogger
+	// IgnoreRecordNotFoundError log statements failing with ErrRecordNotFound as successful
+	IgnoreRecordNotFoundError bool
+}
+
+func NewGormLogger() *GormLogger {
+	return NewGormLoggerByConfig(&SQLLogConfig{})
+}
+
+// NewGormLoggerByConfig
+/**
+ * @Description: gorm logger at logger.Info with slow query detection and sampling as configured by cfg
+ * @param cfg
+ * @return *GormLogger
+ */
+func NewGormLoggerByConfig(cfg *SQLLogConfig) *GormLogger {
+	return &GormLogger{level: glogger.Info, sql: newSQLLogger(*cfg)}
+}
+
+// LogMode implements logger.Interface, the copy shares stats with l
+func (l *GormLogger) LogMode(level glogger.LogLevel) glogger.Interface {
+	c := *l
+	c.level = level
+	if c.sql == nil {
+		c.sql = newSQLLogger(SQLLogConfig{})
+	}
+	return &c
+}
+
+// Info implements logger.Interface
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.logf(ctx, zapcore.InfoLevel, msg, data)
+}
+
+// Warn implements logger.Interface
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.logf(ctx, zapcore.WarnLevel, msg, data)
+}
+
+// Error implements logger.Interface
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.logf(ctx, zapcore.ErrorLevel, msg, data)
+}
+
+// Trace implements logger.Interface
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.sql == nil {
+		// zero value, silent and without stats
+		return
+	}
+	if l.IgnoreRecordNotFoundError && errors.Is(err, glogger.ErrRecordNotFound) {
+		err = nil
+	}
+	// observed before the level check, Stats count silent sessions too
+	sql, rows := fc()
+	e := l.sql.observe(sql, nil, time.Since(begin), err)
+	if e.skip || l.level <= glogger.Silent || !l.enabled(e.level) {
+		return
+	}
+	l.write(ctx, e, rows, err)
+}
+
+// write e with the context fields of ctx, in the message format of OrmLoggerAdapter.AfterSQL
+func (l *GormLogger) write(ctx context.Context, e sqlEntry, rows int64, err error) {
+	msg := fmt.Sprintf("[SQL]Part: %s Args: %v Rows %d Err %v", "", e.args, rows, err)
+	if ce := _gGormLogger.Check(e.level, msg); ce != nil {
+		ce.Write(append(Ctx2Fields(ctx), e.fields...)...)
+	}
+}
+
+// logf formatted message at lvl when the gorm level allows it
+func (l *GormLogger) logf(ctx context.Context, lvl zapcore.Level, msg string, data []interface{}) {
+	if !l.enabled(lvl) || !_gGormLogger.Core().Enabled(lvl) {
+		return
+	}
+	if ce := _gGormLogger.Check(lvl, fmt.Sprintf(msg, data...)); ce != nil {
+		ce.Write(Ctx2Fields(ctx)...)
+	}
+}
+
+// enabled entries at lvl pass the gorm level
+func (l *GormLogger) enabled(lvl zapcore.Level) bool {
+	switch {
+	case lvl >= zapcore.ErrorLevel:
+		return l.level >= glogger.Error
+	case lvl == zapcore.WarnLevel:
+		return l.level >= glogger.Warn
+	default:
+		return l.level >= glogger.Info
+	}
+}
+
+// Stats
+/**
+ * @Description: count, errors and latency percentiles per statement fingerprint, most executed first
+ * @receiver l
+ * @return []SQLStat
+ */
+func (l *GormLogger) Stats() []SQLStat {
+	if l.sql == nil {
+		return nil
+	}
+	return l.sql.snapshot()
+}
+
+// ResetStats forget the stats collected so far
+func (l *GormLogger) ResetStats() {
+	if l.sql != nil {
+		l.sql.reset()
+	}
+}
+
+// StatsHandler
+/**
+ * @Description: serves Stats as JSON, e.g. mounted on an admin port
+ * @receiver l
+ * @return http.Handler
+ */
+func (l *GormLogger) StatsHandler() http.Handler {
+	return sqlStatsHandler(l.Stats)
+}
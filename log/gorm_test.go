@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	glogger "gorm.io/gorm/logger"
+)
+
+var _ glogger.Interface = &GormLogger{}
+
+func TestGormLogger_Trace(t *testing.T) {
+	buf := xormOutput(t)
+	l := NewGormLoggerByConfig(&SQLLogConfig{SlowThreshold: time.Second})
+	ctx := context.WithValue(context.Background(), RequestIdKey, "r1")
+	stmt := func(sql string, rows int64) func() (string, int64) {
+		return func() (string, int64) { return sql, rows }
+	}
+
+	l.Trace(ctx, time.Now(), stmt("UPDATE t SET a = 1", 3), nil)
+	l.Trace(ctx, time.Now().Add(-2*time.Second), stmt("SELECT * FROM t", 0), nil)
+	l.Trace(ctx, time.Now(), stmt("SELECT * FROM u", 0), errors.New("boom"))
+	lines := xormLines(t, buf)
+	if len(lines) != 3 {
+		t.Fatalf("lines => %v", lines)
+	}
+	for _, line := range lines {
+		if line["logger"] != "gorm" || line[Category.ToString()] != "SQL" || line[SessionId.ToString()] != "r1" || line[QueryText.ToString()] == nil {
+			t.Errorf("fields => %v", line)
+		}
+	}
+	if lines[0]["level"] != "INFO" || lines[1]["level"] != "WARN" || lines[1]["slow"] != true || lines[2]["level"] != "ERROR" || lines[2][Errors.ToString()] != "boom" {
+		t.Errorf("levels => %v", lines)
+	}
+
+	warn := l.LogMode(glogger.Warn)
+	warn.Trace(ctx, time.Now(), stmt("SELECT 1", 0), nil)
+	warn.Info(ctx, "dropped %d", 1)
+	warn.Warn(ctx, "kept %d", 1)
+	l.LogMode(glogger.Silent).Error(ctx, "dropped")
+	l.LogMode(glogger.Silent).Trace(ctx, time.Now(), stmt("DELETE FROM w", 1), nil)
+	l.IgnoreRecordNotFoundError = true
+	l.Trace(ctx, time.Now(), stmt("SELECT * FROM v", 0), glogger.ErrRecordNotFound)
+	lines = xormLines(t, buf)
+	if len(lines) != 2 || lines[0]["msg"] != "kept 1" || lines[0][SessionId.ToString()] != "r1" || lines[1]["level"] != "INFO" {
+		t.Errorf("log mode => %v", lines)
+	}
+	if stats := l.Stats(); len(stats) != 6 {
+		t.Errorf("stats shared with LogMode copies => %v", stats)
+	}
+}
+
+func TestGormLogger_ZeroValue(t *testing.T) {
+	buf := xormOutput(t)
+	var l GormLogger
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("boom"))
+	l.Error(context.Background(), "dropped")
+	if lines := xormLines(t, buf); len(lines) != 0 {
+		t.Errorf("zero value logged => %v", lines)
+	}
+	if stats := l.Stats(); len(stats) != 0 {
+		t.Errorf("stats => %v", stats)
+	}
+}
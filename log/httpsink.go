@@ -0,0 +1,471 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Protocols of HTTPSinkConfig.Protocol
+const (
+	ProtocolLoki          = "loki"
+	ProtocolElasticsearch = "elasticsearch"
+)
+
+const (
+	defaultHTTPBatchSize     = 100
+	defaultHTTPMaxPending    = 10000
+	defaultHTTPFlushInterval = time.Second
+	defaultHTTPTimeout       = 5 * time.Second
+	defaultHTTPMaxRetries    = 3
+	defaultHTTPRetryBackoff  = 500 * time.Millisecond
+	defaultHTTPCloseTimeout  = 5 * time.Second
+	defaultESIndex           = "logs"
+)
+
+var errHTTPClosed = errors.New("log: http writer closed")
+
+// HTTPSinkConfig
+/**
+ * @Description: serializes HTTP batch push sink config in toml/json/yaml.
+ * Loki receives the push API JSON, Elasticsearch the bulk API NDJSON.
+ */
+type HTTPSinkConfig struct {
+	URL           string            `toml:"url" json:"url" yaml:"url"`                               // e.g. http://loki:3100/loki/api/v1/push or http://es:9200/_bulk.
+	Protocol      string            `toml:"protocol" json:"protocol" yaml:"protocol"`                // loki or elasticsearch, default loki.
+	Index         string            `toml:"index" json:"index" yaml:"index"`                         // Elasticsearch index, default logs.
+	Labels        map[string]string `toml:"labels" json:"labels" yaml:"labels"`                      // Loki stream labels, level is added.
+	Headers       map[string]string `toml:"headers" json:"headers" yaml:"headers"`                   // Extra request headers, e.g. Authorization.
+	BatchSize     int               `toml:"batchSize" json:"batchSize" yaml:"batchSize"`             // Entries per request, default 100.
+	MaxPending    int               `toml:"maxPending" json:"maxPending" yaml:"maxPending"`          // Entries held while sends fail, oldest dropped beyond, default 10000.
+	FlushInterval time.Duration     `toml:"flushInterval" json:"flushInterval" yaml:"flushInterval"` // Partial batches are sent this often, default 1s.
+	Timeout       time.Duration     `toml:"timeout" json:"timeout" yaml:"timeout"`                   // Per request, default 5s.
+	MaxRetries    int               `toml:"maxRetries" json:"maxRetries" yaml:"maxRetries"`          // Retries of a failed request, default 3.
+	RetryBackoff  time.Duration     `toml:"retryBackoff" json:"retryBackoff" yaml:"retryBackoff"`    // Delay before the first retry, doubled after each, default 500ms.
+	CloseTimeout  time.Duration     `toml:"closeTimeout" json:"closeTimeout" yaml:"closeTimeout"`    // Bound on Sync and Close, what is left is spooled or dropped, default 5s.
+	Spool         SpoolConfig       `toml:"spool" json:"spool" yaml:"spool"`                         // Keep batches on disk while the endpoint is down, off by default.
+}
+
+// Validate
+/**
+ * @Description: check url, protocol and for negative values
+ * @receiver c
+ * @return error
+ */
+func (c *HTTPSinkConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("log.sinks.http: url is required")
+	}
+	switch c.Protocol {
+	case "", ProtocolLoki, ProtocolElasticsearch:
+	default:
+		return fmt.Errorf("log.sinks.http: unknown protocol '%s'", c.Protocol)
+	}
+	if c.BatchSize < 0 || c.MaxPending < 0 || c.FlushInterval < 0 || c.Timeout < 0 || c.MaxRetries < 0 || c.RetryBackoff < 0 || c.CloseTimeout < 0 {
+		return fmt.Errorf("log.sinks.http: batchSize/maxPending/flushInterval/timeout/maxRetries/retryBackoff/closeTimeout must not be negative")
+	}
+	if c.Spool.MaxSize < 0 {
+		return fmt.Errorf("log.sinks.http.spool: maxSize must not be negative")
+	}
+	return nil
+}
+
+type httpEntry struct {
+	lvl  zapcore.Level
+	time time.Time
+	line []byte
+}
+
+// HTTPWriter
+/**
+ * @Description: batches entries and pushes them to a log endpoint from a
+ * background goroutine. Failed requests are retried with backoff, a batch
+ * still failing after MaxRetries is dropped and counted, or written to the
+ * spool when one is configured. Spooled batches are sent before new ones, so
+ * the endpoint sees entries in order once it is back.
+ */
+type HTTPWriter struct {
+	cfg     HTTPSinkConfig
+	client  *http.Client
+	dropped uint64
+
+	mu      sync.Mutex
+	pending []httpEntry
+	closed  bool
+
+	sendSem chan struct{} // one flush at a time, keeps batches in order, taken within the caller's ctx
+	spool   *spool        // guarded by sendSem, nil without Spool.Dir
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	// runCtx requests of the background goroutine, cancelled when Close runs out of time
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+}
+
+// NewHTTPWriter
+/**
+ * @Description: writer pushing to cfg.URL, runs until Close
+ * @param cfg
+ * @return *HTTPWriter
+ * @return error
+ */
+func NewHTTPWriter(cfg HTTPSinkConfig) (*HTTPWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = ProtocolLoki
+	}
+	if cfg.Index == "" {
+		cfg.Index = defaultESIndex
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultHTTPBatchSize
+	}
+	if cfg.MaxPending == 0 {
+		cfg.MaxPending = defaultHTTPMaxPending
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaultHTTPFlushInterval
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultHTTPMaxRetries
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = defaultHTTPRetryBackoff
+	}
+	if cfg.CloseTimeout == 0 {
+		cfg.CloseTimeout = defaultHTTPCloseTimeout
+	}
+	w := &HTTPWriter{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		kick:    make(chan struct{}, 1),
+		sendSem: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if cfg.Spool.Dir != "" {
+		s, err := openSpool(cfg.Spool)
+		if err != nil {
+			return nil, fmt.Errorf("log.sinks.http.spool: %w", err)
+		}
+		w.spool = s
+	}
+	w.runCtx, w.cancelRun = context.WithCancel(context.Background())
+	go w.run()
+	return w, nil
+}
+
+// Write implements io.Writer, entries written without level count as info
+func (w *HTTPWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zapcore.InfoLevel, p)
+}
+
+// WriteLevel implements LevelWriter
+func (w *HTTPWriter) WriteLevel(lvl zapcore.Level, p []byte) (int, error) {
+	// the encoder reuses its buffer
+	e := httpEntry{lvl: lvl, time: time.Now(), line: append([]byte(nil), bytes.TrimRight(p, "\n")...)}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, errHTTPClosed
+	}
+	if len(w.pending) >= w.cfg.MaxPending {
+		w.pending[0] = httpEntry{}
+		w.pending = w.pending[1:]
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	w.pending = append(w.pending, e)
+	full := len(w.pending) >= w.cfg.BatchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, sends everything written so far
+// within CloseTimeout, the rest is spooled or dropped
+func (w *HTTPWriter) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.CloseTimeout)
+	defer cancel()
+	return w.flush(ctx)
+}
+
+// Close send what is pending and stop the background goroutine, gives up
+// after CloseTimeout and spools or drops the rest
+func (w *HTTPWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.CloseTimeout)
+	defer cancel()
+	close(w.stop)
+	var late error
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		// abort the request in flight, its batch is spooled or dropped
+		w.cancelRun()
+		<-w.done
+		late = ctx.Err()
+	}
+	defer w.cancelRun()
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	return late
+}
+
+// Dropped number of entries lost to failed requests, a full pending queue or the spool size cap
+func (w *HTTPWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *HTTPWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		case <-w.stop:
+			return
+		}
+		// errors are counted in dropped, the next batch tries again
+		_ = w.flush(w.runCtx)
+	}
+}
+
+// flush send spooled then pending entries batch by batch, a failed batch is
+// spooled or dropped, as is everything left once ctx is done. Returns
+// ctx.Err() when a flush in progress outlasts ctx.
+func (w *HTTPWriter) flush(ctx context.Context) error {
+	select {
+	case w.sendSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-w.sendSem }()
+	if w.spool != nil {
+		// pick up batches of writers sharing the dir, e.g. during a reload
+		_ = w.spool.scan()
+		w.replay(ctx)
+	}
+	var first error
+	for {
+		w.mu.Lock()
+		n := len(w.pending)
+		if n > w.cfg.BatchSize {
+			n = w.cfg.BatchSize
+		}
+		batch := append([]httpEntry(nil), w.pending[:n]...)
+		w.pending = w.pending[n:]
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			return first
+		}
+		var err error
+		if w.spool != nil && (!w.spool.empty() || ctx.Err() != nil) {
+			// older batches are still waiting or time is up, queue behind them
+			err = w.store(batch)
+		} else if ctx.Err() != nil {
+			err = ctx.Err()
+		} else if retry, sendErr := w.send(ctx, batch); sendErr != nil {
+			err = sendErr
+			if retry && w.spool != nil {
+				err = w.store(batch)
+			}
+		}
+		if err != nil {
+			atomic.AddUint64(&w.dropped, uint64(len(batch)))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+}
+
+// store write batch to the spool, counting what the size cap pushed out
+func (w *HTTPWriter) store(batch []httpEntry) error {
+	evicted, err := w.spool.push(batch)
+	atomic.AddUint64(&w.dropped, uint64(evicted))
+	return err
+}
+
+// replay send spooled batches oldest first, stops at the first one the
+// endpoint may still take later. Each gets a single attempt per flush, the
+// flush interval is the backoff.
+func (w *HTTPWriter) replay(ctx context.Context) {
+	for !w.spool.empty() && ctx.Err() == nil {
+		batch, err := w.spool.peek()
+		if os.IsNotExist(err) {
+			// replayed by another writer sharing the dir
+			w.spool.remove()
+			continue
+		}
+		if err == nil {
+			var body []byte
+			var contentType string
+			if body, contentType, err = w.encode(batch); err == nil {
+				var retry bool
+				if retry, err = w.post(ctx, body, contentType); err != nil && retry {
+					return
+				}
+			}
+		}
+		n := w.spool.remove()
+		if err != nil {
+			// unreadable or rejected, retrying will not help
+			atomic.AddUint64(&w.dropped, uint64(n))
+		}
+	}
+}
+
+// send one batch, retrying server errors, 429 and transport errors. retry
+// reports whether a later attempt could still succeed.
+func (w *HTTPWriter) send(ctx context.Context, batch []httpEntry) (retry bool, err error) {
+	body, contentType, err := w.encode(batch)
+	if err != nil {
+		return false, err
+	}
+	backoff := w.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err = w.post(ctx, body, contentType)
+		if err == nil {
+			return false, nil
+		}
+		if !retry || attempt >= w.cfg.MaxRetries || !w.wait(ctx, backoff) {
+			return retry, err
+		}
+		backoff *= 2
+	}
+}
+
+func (w *HTTPWriter) post(ctx context.Context, body []byte, contentType string) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		err = fmt.Errorf("log: push to %s: %s %s", w.cfg.URL, resp.Status, bytes.TrimSpace(respBody))
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+	}
+	if w.cfg.Protocol == ProtocolElasticsearch {
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &result) == nil && result.Errors {
+			// rejected documents will be rejected again
+			return false, fmt.Errorf("log: push to %s: bulk request had item errors", w.cfg.URL)
+		}
+	}
+	return false, nil
+}
+
+// wait d unless the writer is closing or ctx is done
+func (w *HTTPWriter) wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-w.stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (w *HTTPWriter) encode(batch []httpEntry) ([]byte, string, error) {
+	if w.cfg.Protocol == ProtocolElasticsearch {
+		return w.encodeBulk(batch), "application/x-ndjson", nil
+	}
+	body, err := w.encodeLoki(batch)
+	return body, "application/json", err
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLoki one stream per level, entries keep their order within it
+func (w *HTTPWriter) encodeLoki(batch []httpEntry) ([]byte, error) {
+	streams := make([]*lokiStream, 0, 2)
+	byLevel := make(map[zapcore.Level]*lokiStream)
+	for _, e := range batch {
+		s, ok := byLevel[e.lvl]
+		if !ok {
+			labels := make(map[string]string, len(w.cfg.Labels)+1)
+			for k, v := range w.cfg.Labels {
+				labels[k] = v
+			}
+			labels["level"] = e.lvl.String()
+			s = &lokiStream{Stream: labels}
+			byLevel[e.lvl] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), string(e.line)})
+	}
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
+
+// encodeBulk index action plus document per entry, lines that are not JSON objects are wrapped
+func (w *HTTPWriter) encodeBulk(batch []httpEntry) []byte {
+	action, _ := json.Marshal(map[string]map[string]string{"index": {"_index": w.cfg.Index}})
+	var buf bytes.Buffer
+	for _, e := range batch {
+		buf.Write(action)
+		buf.WriteByte('\n')
+		if len(e.line) > 0 && e.line[0] == '{' && json.Valid(e.line) {
+			buf.Write(e.line)
+		} else {
+			doc, _ := json.Marshal(map[string]string{
+				"@timestamp": e.time.UTC().Format("2006-01-02T15:04:05.000Z"),
+				"log.level":  e.lvl.String(),
+				"message":    string(e.line),
+			})
+			buf.Write(doc)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -69,3 +69,15 @@ func (l Level) zapLevel() zapcore.Level {
 	}
 	return zapcore.InfoLevel
 }
+
+func fromZapLevel(z zapcore.Level) Level {
+	for l, zl := range zapLevels {
+		if zl == z && l != CriticalLevel {
+			return l
+		}
+	}
+	if z > zapcore.ErrorLevel {
+		return CriticalLevel
+	}
+	return InfoLevel
+}
@@ -27,7 +27,7 @@ func Debug(msg string, fields ...zap.Field) {
  * @param fields
  */
 func DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, DebugLevel, GetLogger().Debug, fields...)
+	logOutputCtx(ctx, msg, DebugLevel, (*zap.Logger).Debug, fields...)
 }
 
 // Info
@@ -48,7 +48,7 @@ func Info(msg string, fields ...zap.Field) {
  * @param fields
  */
 func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, InfoLevel, GetLogger().Info, fields...)
+	logOutputCtx(ctx, msg, InfoLevel, (*zap.Logger).Info, fields...)
 }
 
 // Warn
@@ -61,7 +61,7 @@ func Warn(msg string, fields ...zap.Field) {
 	WarnCtx(context.TODO(), msg, fields...)
 }
 func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, WarnLevel, GetLogger().Warn, fields...)
+	logOutputCtx(ctx, msg, WarnLevel, (*zap.Logger).Warn, fields...)
 }
 
 // Error
@@ -82,7 +82,7 @@ func Error(msg string, fields ...zap.Field) {
  * @param fields
  */
 func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, ErrorLevel, GetLogger().Error, fields...)
+	logOutputCtx(ctx, msg, ErrorLevel, (*zap.Logger).Error, fields...)
 }
 
 // Panic
@@ -103,76 +103,72 @@ func Panic(msg string, fields ...zap.Field) {
  * @param fields
  */
 func PanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, CriticalLevel, GetLogger().Panic, fields...)
+	logOutputCtx(ctx, msg, CriticalLevel, (*zap.Logger).Panic, fields...)
 }
 
 // Fatal
 /**
- * @Description:
+ * @Description: log at fatal level, flush and close every sink and exit with Config.ExitCode
  * @param msg
  * @param fields
- */func Fatal(msg string, fields ...zap.Field) {
+ */
+func Fatal(msg string, fields ...zap.Field) {
 	FatalCtx(context.TODO(), msg, fields...)
 }
 
 // FatalCtx
 /**
- * @Description:
+ * @Description: log at fatal level, flush and close every sink and exit with Config.ExitCode
  * @param ctx
  * @param msg
  * @param fields
  */
 func FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
-	logOutputCtx(ctx, msg, CriticalLevel, GetLogger().Panic, fields...)
+	logOutputCtx(ctx, msg, CriticalLevel, writeFatal, fields...)
+	exitFatal()
 }
 
 // logOutputCtx
 /**
- * @Description:
+ * @Description: log through the global logger, or its debug variant when ctx was raised by ContextWithDebug
  * @param ctx
  * @param msg
  * @param level
  * @param logFunc
  * @param fields
  */
-func logOutputCtx(ctx context.Context, msg string, level Level, logFunc func(string, ...zap.Field), fields ...zap.Field) {
-	if GetLevel().Unabled(level) {
+func logOutputCtx(ctx context.Context, msg string, level Level, logFunc func(*zap.Logger, string, ...zap.Field), fields ...zap.Field) {
+	logger := GetLogger()
+	if DebugFromContext(ctx) {
+		logger = GetProps().debugLogger(logger)
+	} else if ctx != nil {
+		// low level entries of a buffered request go to its buffer
+		if rid, ok := ctx.Value(RequestIdKey).(string); ok {
+			if rl := GetProps().requestLogger(logger, rid); rl != nil {
+				logger = rl
+			}
+		}
+	}
+	if !logger.Core().Enabled(level.zapLevel()) {
 		return
 	}
 	fields = append(fields, Ctx2Fields(ctx)...)
-	logFunc(msg, fields...)
+	logFunc(logger, msg, fields...)
 }
 
 // Ctx2Fields
 /**
- * @Description:
+ * @Description: fields of every registered CtxExtractor, safe for a nil ctx
  * @param ctx
  * @return []zap.Field
  */
 func Ctx2Fields(ctx context.Context) []zap.Field {
 	fields := make([]zap.Field, 0)
-	if ctx != nil && ctx != context.TODO() {
-		if v := ctx.Value(RequestIdKey); v != nil {
-			rid := v.(string)
-			fields = append(fields, zap.String(SessionId.ToString(), rid))
-			fields = append(fields, zap.String(TraceId.ToString(), rid))
-		}
-		if v := ctx.Value(BusinessKeyword); v != nil {
-			kw := v.(string)
-			fields = append(fields, zap.String(BusinessKeyword.ToString(), kw))
-		}
-		if v := ctx.Value(BusinessOperation); v != nil {
-			kw := v.(string)
-			fields = append(fields, zap.String(BusinessOperation.ToString(), kw))
-		}
-		if v := ctx.Value(BusinessTitle); v != nil {
-			kw := v.(string)
-			fields = append(fields, zap.String(BusinessTitle.ToString(), kw))
-		}
-		if v := ctx.Value(BusinessUserID); v != nil {
-			kw := v.(string)
-			fields = append(fields, zap.String(BusinessUserID.ToString(), kw))
-		}
+	if ctx == nil || ctx == context.TODO() || ctx == context.Background() {
+		return fields
+	}
+	for _, e := range _gExtractors.Load().([]namedExtractor) {
+		fields = append(fields, extract(ctx, e)...)
 	}
 	return fields
 }
@@ -187,15 +183,19 @@ func With(fields ...zap.Field) *zap.Logger {
 	return GetLogger().WithOptions(zap.AddCallerSkip(1)).With(fields...)
 }
 
-// SetLevel alters the logging level.
+// SetLevel alters the logging level of the file core.
 /**
- * @Description:
+ * @Description: takes effect for every logger built by InitLogger, including With and GetLogger users
  * @param levelStr
+ * @return error unknown level, the level is left as is
  */
-func SetLevel(levelStr string) {
+func SetLevel(levelStr string) error {
 	l := new(Level)
-	l.Unpack(levelStr)
-	_gProps.Load().(*ZapProperties).Level = l
+	if err := l.Unpack(levelStr); err != nil {
+		return err
+	}
+	GetProps().AtomicLevel.SetLevel(l.zapLevel())
+	return nil
 }
 
 // GetLevel
@@ -204,5 +204,31 @@ func SetLevel(levelStr string) {
  * @return *Level
  */
 func GetLevel() *Level {
-	return _gProps.Load().(*ZapProperties).Level
+	l := fromZapLevel(GetProps().AtomicLevel.Level())
+	return &l
+}
+
+// SetStdLevel alters the logging level of the console core.
+/**
+ * @Description:
+ * @param levelStr
+ * @return error unknown level, the level is left as is
+ */
+func SetStdLevel(levelStr string) error {
+	l := new(Level)
+	if err := l.Unpack(levelStr); err != nil {
+		return err
+	}
+	GetProps().StdLevel.SetLevel(l.zapLevel())
+	return nil
+}
+
+// GetStdLevel
+/**
+ * @Description:
+ * @return *Level
+ */
+func GetStdLevel() *Level {
+	l := fromZapLevel(GetProps().StdLevel.Level())
+	return &l
 }
@@ -1,8 +1,13 @@
 package log
 
 import (
+	"bytes"
 	"log"
+	"strings"
 	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestUpdateLevel(t *testing.T) {
@@ -36,3 +41,61 @@ func TestNoCfg(t *testing.T) {
 	Info("info")
 	Error("error")
 }
+
+func TestSetLevel_Runtime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "error", Format: "json"}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+
+	direct := GetLogger()
+	child := With(zap.String("k", "v"))
+	direct.Debug("hidden")
+	SetLevel("debug")
+	direct.Debug("direct debug")
+	child.Debug("child debug")
+	SetLevel("error")
+	Warn("hidden warn")
+	child.Warn("hidden child warn")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("disabled entries written: %s", out)
+	}
+	if !strings.Contains(out, "direct debug") || !strings.Contains(out, "child debug") {
+		t.Errorf("runtime level not applied: %s", out)
+	}
+	if GetLevel().String() != "error" || GetStdLevel().String() != "error" {
+		t.Errorf("levels => %s %s", GetLevel(), GetStdLevel())
+	}
+	SetStdLevel("debug")
+	if GetStdLevel().String() != "debug" || GetLevel().String() != "error" {
+		t.Errorf("std level => %s file level => %s", GetStdLevel(), GetLevel())
+	}
+	if err := SetLevel("verbose"); err == nil || GetLevel().String() != "error" {
+		t.Errorf("unknown level => %v %s", err, GetLevel())
+	}
+	if p.Level == nil || p.Level.String() != "info" {
+		t.Errorf("built level => %v", p.Level)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	defer New(nil)
+	if err := New(&Config{Level: "info", Format: "yaml"}); err == nil {
+		t.Error("unknown format accepted")
+	}
+
+	// props made by hand with only the level they were built for
+	lv := WarnLevel
+	Reset(zap.NewNop(), &ZapProperties{Core: zapcore.NewNopCore(), Level: &lv})
+	if GetLevel().String() != "warning" || GetStdLevel().String() != "warning" {
+		t.Errorf("levels => %s %s", GetLevel(), GetStdLevel())
+	}
+	if err := SetLevel("error"); err != nil || GetLevel().String() != "error" {
+		t.Errorf("set level => %v %s", err, GetLevel())
+	}
+}
@@ -0,0 +1,230 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _gLogfmtPool = buffer.NewPool()
+
+// logfmtEncoder
+/**
+ * @Description: key=value pairs separated by spaces. Nested objects are
+ * flattened into dotted keys, arrays and reflected values are written as JSON.
+ */
+type logfmtEncoder struct {
+	cfg    zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string // dotted path of the object being flattened
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: cfg, buf: _gLogfmtPool.Get()}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{cfg: e.cfg, buf: _gLogfmtPool.Get(), prefix: e.prefix}
+	_, _ = clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{cfg: e.cfg, buf: _gLogfmtPool.Get()}
+	if e.cfg.TimeKey != "" && e.cfg.TimeKey != zapcore.OmitKey {
+		final.AddString(e.cfg.TimeKey, ent.Time.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	if e.cfg.LevelKey != "" && e.cfg.LevelKey != zapcore.OmitKey {
+		final.AddString(e.cfg.LevelKey, ent.Level.CapitalString())
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != "" && e.cfg.NameKey != zapcore.OmitKey {
+		final.AddString(e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != "" && e.cfg.CallerKey != zapcore.OmitKey {
+		final.AddString(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" && e.cfg.MessageKey != zapcore.OmitKey {
+		final.AddString(e.cfg.MessageKey, ent.Message)
+	}
+	if e.buf.Len() > 0 {
+		final.sep()
+		_, _ = final.buf.Write(e.buf.Bytes())
+	}
+	final.prefix = e.prefix
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.prefix = ""
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" && e.cfg.StacktraceKey != zapcore.OmitKey {
+		final.AddString(e.cfg.StacktraceKey, ent.Stack)
+	}
+	lineEnding := e.cfg.LineEnding
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	final.buf.AppendString(lineEnding)
+	return final.buf, nil
+}
+
+func (e *logfmtEncoder) sep() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+func (e *logfmtEncoder) key(key string) {
+	e.sep()
+	e.appendKey(e.prefix + key)
+	e.buf.AppendByte('=')
+}
+
+// appendKey keys are written bare, characters logfmt cannot carry become _
+func (e *logfmtEncoder) appendKey(key string) {
+	if key == "" {
+		e.buf.AppendByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			e.buf.AppendByte('_')
+			continue
+		}
+		e.buf.AppendString(string(r))
+	}
+}
+
+// appendValue quotes values that are empty or hold spaces, quotes, = or control characters
+func (e *logfmtEncoder) appendValue(v string) {
+	if v == "" {
+		e.buf.AppendString(`""`)
+		return
+	}
+	for _, r := range v {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError {
+			e.buf.AppendString(strconv.Quote(v))
+			return
+		}
+	}
+	e.buf.AppendString(v)
+}
+
+func (e *logfmtEncoder) appendJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.appendValue(string(b))
+	return nil
+}
+
+func (e *logfmtEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	// collect the array through a map encoder, then write it as JSON
+	enc := zapcore.NewMapObjectEncoder()
+	if err := enc.AddArray(key, m); err != nil {
+		return err
+	}
+	e.key(key)
+	return e.appendJSON(enc.Fields[key])
+}
+
+func (e *logfmtEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	old := e.prefix
+	e.prefix = old + key + "."
+	err := m.MarshalLogObject(e)
+	e.prefix = old
+	return err
+}
+
+func (e *logfmtEncoder) AddBinary(key string, v []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, v []byte) { e.AddString(key, string(v)) }
+
+func (e *logfmtEncoder) AddBool(key string, v bool) {
+	e.key(key)
+	e.buf.AppendBool(v)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, v complex128) {
+	e.key(key)
+	e.buf.AppendString(strconv.FormatComplex(v, 'g', -1, 128))
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, v complex64) {
+	e.AddComplex128(key, complex128(v))
+}
+
+// AddDuration seconds, the same as the console and json formats
+func (e *logfmtEncoder) AddDuration(key string, v time.Duration) {
+	e.AddFloat64(key, v.Seconds())
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, v float64) {
+	e.key(key)
+	switch {
+	case math.IsNaN(v):
+		e.buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(v, 64)
+	}
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, v float32) { e.AddFloat64(key, float64(v)) }
+
+func (e *logfmtEncoder) AddInt64(key string, v int64) {
+	e.key(key)
+	e.buf.AppendInt(v)
+}
+
+func (e *logfmtEncoder) AddInt(key string, v int)     { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt32(key string, v int32) { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt16(key string, v int16) { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt8(key string, v int8)   { e.AddInt64(key, int64(v)) }
+
+func (e *logfmtEncoder) AddString(key, v string) {
+	e.key(key)
+	e.appendValue(v)
+}
+
+func (e *logfmtEncoder) AddTime(key string, v time.Time) {
+	e.AddString(key, v.UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+func (e *logfmtEncoder) AddUint64(key string, v uint64) {
+	e.key(key)
+	e.buf.AppendUint(v)
+}
+
+func (e *logfmtEncoder) AddUint(key string, v uint)       { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint32(key string, v uint32)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint16(key string, v uint16)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint8(key string, v uint8)     { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUintptr(key string, v uintptr) { e.AddUint64(key, uint64(v)) }
+
+func (e *logfmtEncoder) AddReflected(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		e.AddString(key, fmt.Sprintf("%+v", v))
+		return nil
+	}
+	e.key(key)
+	e.appendValue(string(b))
+	return nil
+}
+
+// OpenNamespace prefixes the keys added after it
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
@@ -1,18 +1,16 @@
 package log
 
 import (
+	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
 	"os"
 	"path"
 	"sync/atomic"
 	"time"
 
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -21,7 +19,22 @@ var (
 	_gSugar  atomic.Value
 )
 
-func New(cfg *Config) {
+// discard everything until New or Reset is called
+func init() {
+	Reset(zap.NewNop(), &ZapProperties{
+		Core:   zapcore.NewNopCore(),
+		Syncer: zapcore.AddSync(ioutil.Discard),
+	})
+}
+
+// New
+/**
+ * @Description: build the global logger from cfg, a nil cfg logs info and up
+ * to stdout. The running logger is kept when cfg is invalid.
+ * @param cfg
+ * @return error
+ */
+func New(cfg *Config) error {
 	if cfg == nil {
 		cfg = &Config{
 			Level:  "info",
@@ -31,11 +44,39 @@ func New(cfg *Config) {
 			},
 		}
 	}
-	l, p, _ := InitLogger(cfg)
+	l, p, err := InitLogger(cfg)
+	if err != nil {
+		return err
+	}
 	Reset(l, p)
+	return nil
 }
 
+// Reset
+/**
+ * @Description: install logger and props as the global logger. Atomic levels
+ * left unset start at props.Level, debug without it.
+ * @param logger
+ * @param props
+ */
 func Reset(logger *zap.Logger, props *ZapProperties) {
+	if props.AtomicLevel == (zap.AtomicLevel{}) {
+		lv := DebugLevel
+		if props.Level != nil {
+			lv = *props.Level
+		}
+		props.AtomicLevel = zap.NewAtomicLevelAt(lv.zapLevel())
+	}
+	if props.StdLevel == (zap.AtomicLevel{}) {
+		props.StdLevel = zap.NewAtomicLevelAt(props.AtomicLevel.Level())
+	}
+	for name, lvl := range props.modules {
+		_gModules.get(name).setLevel(lvl)
+	}
+	if props.live != nil {
+		// loggers derived from the previous one follow a Reload
+		props.live.store(props.Core)
+	}
 	_gLogger.Store(logger)
 	_gSugar.Store(logger.Sugar())
 	_gProps.Store(props)
@@ -45,10 +86,23 @@ func GetLogger() *zap.Logger {
 	return _gLogger.Load().(*zap.Logger)
 }
 
+func GetProps() *ZapProperties {
+	return _gProps.Load().(*ZapProperties)
+}
+
 func GetSurgar() *zap.SugaredLogger {
 	return _gSugar.Load().(*zap.SugaredLogger)
 }
 
+// Dropped
+/**
+ * @Description: entries dropped by the async writer overflow policy and by sinks that failed to deliver
+ * @return uint64
+ */
+func Dropped() uint64 {
+	return GetProps().dropped()
+}
+
 func Sync() error {
 	err := GetLogger().Sync()
 	if err != nil {
@@ -59,20 +113,60 @@ func Sync() error {
 
 // InitLogger initializes a zap logger.
 func InitLogger(cfg *Config, opts ...zap.Option) (*zap.Logger, *ZapProperties, error) {
-	var output zapcore.WriteSyncer
+	return buildLogger(cfg, nil, opts...)
+}
+
+// buildLogger InitLogger taking over from prev, when not nil, the core
+// behind its logger, its levels and the writers whose config is unchanged
+func buildLogger(cfg *Config, prev *ZapProperties, opts ...zap.Option) (*zap.Logger, *ZapProperties, error) {
+	var (
+		output  zapcore.WriteSyncer
+		file    zapcore.WriteSyncer
+		fileKey string
+		taken   bool
+	)
 	if len(cfg.File.FileName) > 0 {
-		output = zapcore.AddSync(getWriter(&cfg.File))
-	} else {
+		fileKey = writerKey(SinkFile, cfg.File)
+		if prev != nil {
+			file, taken = prev.writers[fileKey]
+		}
+		if !taken {
+			w, err := NewRotateWriter(&cfg.File)
+			if err != nil {
+				return nil, nil, err
+			}
+			file = w
+		}
+		output = file
+	} else if len(cfg.Sinks) == 0 {
 		stdOut, _, err := zap.Open([]string{"stdout"}...)
 		if err != nil {
 			return nil, nil, err
 		}
 		output = stdOut
 	}
-	return InitLoggerWithWriteSyncer(cfg, output, opts...)
+	l, p, err := initLogger(cfg, output, prev, opts...)
+	if err != nil {
+		if c, ok := file.(io.Closer); ok && !taken {
+			_ = c.Close()
+		}
+		return nil, nil, err
+	}
+	if file != nil {
+		// after the async writer wrapping it, Close goes in order
+		p.addWriter(fileKey, file)
+	}
+	return l, p, nil
 }
 
-func BuildEncoder(format string) zapcore.Encoder {
+// BuildEncoder
+/**
+ * @Description: encoder of a log format, empty is console
+ * @param format console, json, logfmt, ecs or gelf
+ * @return zapcore.Encoder
+ * @return error unknown format
+ */
+func BuildEncoder(format string) (zapcore.Encoder, error) {
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        Datetime.ToString(),
 		LevelKey:       LevelKey.ToString(),
@@ -91,46 +185,131 @@ func BuildEncoder(format string) zapcore.Encoder {
 		enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z"))
 	}
 	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-	var encoder zapcore.Encoder
 	switch format {
-	case "console":
-		encoder = zapcore.NewConsoleEncoder(encoderConfig) // 普通模式
-	case "json":
-		encoder = zapcore.NewJSONEncoder(encoderConfig) // json格式
+	case "", FormatConsole:
+		return zapcore.NewConsoleEncoder(encoderConfig), nil // 普通模式
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil // json格式
+	case FormatLogfmt:
+		return newLogfmtEncoder(encoderConfig), nil
+	case FormatECS:
+		return newECSEncoder(), nil
+	case FormatGELF:
+		return newGELFEncoder(), nil
 	default:
-		encoder = zapcore.NewConsoleEncoder(encoderConfig) // 普通模式
+		return nil, fmt.Errorf("log: unknown format '%s'", format)
 	}
-	return encoder
 }
 
 // InitLoggerWithWriteSyncer initializes a zap logger with specified  write syncer.
 func InitLoggerWithWriteSyncer(cfg *Config, output zapcore.WriteSyncer, opts ...zap.Option) (*zap.Logger, *ZapProperties, error) {
-	// get level
-	def := DebugLevel
-	lv := &def
-	stdLevel := &def
+	return initLogger(cfg, output, nil, opts...)
+}
 
-	coreTree := make([]zapcore.Core, 0)
+// initLogger InitLoggerWithWriteSyncer taking over from prev, see buildLogger
+func initLogger(cfg *Config, output zapcore.WriteSyncer, prev *ZapProperties, opts ...zap.Option) (*zap.Logger, *ZapProperties, error) {
+	// get level, both can be changed at runtime
+	lv := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	stdLevel := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	if prev != nil {
+		// set at runtime, they change again only when the config does
+		lv, stdLevel = prev.AtomicLevel, prev.StdLevel
+	}
+
+	def := DebugLevel
+	r := &ZapProperties{
+		Syncer:      output,
+		Level:       &def,
+		AtomicLevel: lv,
+		StdLevel:    stdLevel,
+	}
+	callSkip := 0
 
 	if cfg != nil {
-		lv := cfg.GetLevel()
-		stdLevel = cfg.GetStdLevel()
+		r.Level, r.stdBuilt = cfg.GetLevel(), cfg.GetStdLevel()
+		if prev == nil || prev.Level == nil || *prev.Level != *r.Level {
+			lv.SetLevel(r.Level.zapLevel())
+		}
+		if prev == nil || prev.stdBuilt == nil || *prev.stdBuilt != *r.stdBuilt {
+			stdLevel.SetLevel(r.stdBuilt.zapLevel())
+		}
+		callSkip = cfg.CallSkip
+		if cfg.Redact.Enable {
+			redactor, err := NewRedactor(cfg.Redact)
+			if err != nil {
+				return nil, nil, err
+			}
+			r.redactor = redactor
+		}
 		// build file core
-		fileEncoder := BuildEncoder(cfg.Format)
-		coreTree = append(coreTree, zapcore.NewCore(fileEncoder, output, lv.zapLevel()))
+		fileEncoder, err := BuildEncoder(cfg.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Sampling.Enable {
+			sampling := cfg.Sampling
+			r.sampling = &sampling
+		}
+		if cfg.RateLimit.Enable {
+			r.limiter = NewRateLimiter(cfg.RateLimit)
+		}
+		if cfg.RequestBuffer.Enable {
+			if err := cfg.RequestBuffer.Validate(); err != nil {
+				return nil, nil, err
+			}
+			r.buffers = newRequestBuffers(cfg.RequestBuffer)
+		}
+		if cfg.Crash.Enable {
+			if err := cfg.Crash.Validate(); err != nil {
+				return nil, nil, err
+			}
+			r.crash = newCrashReporter(cfg)
+		}
+		r.exitCode = cfg.ExitCode
+		// module levels, set when the logger is installed
+		for name, levelStr := range cfg.Modules {
+			l := new(Level)
+			if err := l.Unpack(levelStr); err != nil {
+				return nil, nil, err
+			}
+			if r.modules == nil {
+				r.modules = make(map[string]zapcore.Level, len(cfg.Modules))
+			}
+			r.modules[name] = l.zapLevel()
+		}
+		if output != nil {
+			if cfg.Async.Enable {
+				async := NewAsyncWriter(output, cfg.Async)
+				output, r.Syncer = async, async
+				r.closers = append(r.closers, async)
+			}
+			r.sinks = append(r.sinks, sink{encoder: fileEncoder, out: output, level: lv})
+		}
+		if err := r.addSinks(cfg, lv, prev); err != nil {
+			_ = r.closeExcept(prev)
+			return nil, nil, err
+		}
+		if r.crash != nil {
+			tailEncoder, err := BuildEncoder(cfg.Format)
+			if err != nil {
+				_ = r.closeExcept(prev)
+				return nil, nil, err
+			}
+			r.sinks = append(r.sinks, sink{encoder: tailEncoder, out: r.crash.tail, level: lv})
+		}
 	}
 
 	consoleEncoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
-	coreTree = append(coreTree, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), stdLevel.zapLevel()))
-	cores := zapcore.NewTee(coreTree...)
+	r.sinks = append(r.sinks, sink{encoder: consoleEncoder, out: zapcore.Lock(os.Stdout), level: stdLevel})
+	r.Core = r.newCore(nil)
+	if prev != nil && prev.live != nil {
+		r.live = prev.live
+	} else {
+		r.live = newCoreSlot(r.Core)
+	}
 
 	// build log
-	lg := zap.New(cores, zap.AddCaller(), zap.AddCallerSkip(cfg.CallSkip))
-	r := &ZapProperties{
-		Core:   cores,
-		Syncer: output,
-		Level:  lv,
-	}
+	lg := zap.New(&liveCore{slot: r.live}, append([]zap.Option{zap.AddCaller(), zap.AddCallerSkip(callSkip)}, opts...)...)
 	//  replace Globals log
 	zap.ReplaceGlobals(lg)
 	return lg, r, nil
@@ -163,38 +342,3 @@ func initFileLogName(cfg *FileLogConfig) (string, error) {
 		return "app", nil
 	}
 }
-
-// 文件写入器
-// initFileLog initializes file based logging options.
-func initFileLog(cfg *FileLogConfig) (*lumberjack.Logger, error) {
-	// 构建文件名称
-	// Build flie log name
-	targetPath, err := initFileLogName(cfg)
-	if err != nil {
-		return nil, err
-	}
-	// use lumberjack to logrotate
-	return &lumberjack.Logger{
-		Filename:   targetPath,
-		MaxSize:    cfg.MaxSize,
-		MaxBackups: cfg.MaxBackups,
-		MaxAge:     cfg.MaxDays,
-		LocalTime:  true,
-		Compress:   cfg.Compress,
-	}, nil
-}
-
-func getWriter(cfg *FileLogConfig) io.Writer {
-	filename, _ := initFileLogName(cfg)
-	hook, err := rotatelogs.New(
-		filename+".%Y%m%d%H", // 没有使用go风格反人类的format格式
-		rotatelogs.WithLinkName(filename),
-		rotatelogs.WithMaxAge(time.Hour*24*time.Duration(cfg.MaxDays)), // 保存30天
-		rotatelogs.WithRotationTime(time.Hour*24),                      //切割频率 24小时
-	)
-	if err != nil {
-		log.Println("日志启动异常")
-		panic(err)
-	}
-	return hook
-}
@@ -0,0 +1,162 @@
+// Package logtest captures what code under test logs through package log.
+package logtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Observer
+/**
+ * @Description: entries logged since New, through GetLogger, With, Named and the *Ctx functions
+ */
+type Observer struct {
+	logs *observer.ObservedLogs
+}
+
+// New
+/**
+ * @Description: install an observer core through log.Reset, every level is
+ * captured until log.SetLevel changes it. The previous logger is restored when t ends.
+ * @param t
+ * @return *Observer
+ */
+func New(t testing.TB) *Observer {
+	prevLogger, prevProps := mlog.GetLogger(), mlog.GetProps()
+	lv := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	core, logs := observer.New(lv)
+	mlog.Reset(zap.New(core, zap.AddCaller()), &mlog.ZapProperties{
+		Core:        core,
+		Syncer:      zapcore.AddSync(ioutil.Discard),
+		AtomicLevel: lv,
+		StdLevel:    zap.NewAtomicLevelAt(zapcore.DebugLevel),
+	})
+	t.Cleanup(func() {
+		mlog.Reset(prevLogger, prevProps)
+	})
+	return &Observer{logs: logs}
+}
+
+// Entries
+/**
+ * @Description: captured entries in order
+ * @receiver o
+ * @return []observer.LoggedEntry
+ */
+func (o *Observer) Entries() []observer.LoggedEntry {
+	return o.logs.All()
+}
+
+// TakeAll
+/**
+ * @Description: captured entries in order, the observer starts over empty
+ * @receiver o
+ * @return []observer.LoggedEntry
+ */
+func (o *Observer) TakeAll() []observer.LoggedEntry {
+	return o.logs.TakeAll()
+}
+
+// Filter
+/**
+ * @Description: entries at level whose message contains msg and that carry every field
+ * @receiver o
+ * @param level
+ * @param msg substring, empty matches every message
+ * @param fields compared by encoded value
+ * @return []observer.LoggedEntry
+ */
+func (o *Observer) Filter(level zapcore.Level, msg string, fields ...zap.Field) []observer.LoggedEntry {
+	var matched []observer.LoggedEntry
+	for _, e := range o.logs.All() {
+		if e.Level == level && strings.Contains(e.Message, msg) && hasFields(e, fields) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// AssertLogged
+/**
+ * @Description: fail t unless an entry matches, see Filter
+ * @receiver o
+ * @param t
+ * @param level
+ * @param msg
+ * @param fields
+ * @return observer.LoggedEntry the first match
+ */
+func (o *Observer) AssertLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) observer.LoggedEntry {
+	t.Helper()
+	matched := o.Filter(level, msg, fields...)
+	if len(matched) == 0 {
+		t.Errorf("no %s entry containing %q with %s, logged:\n%s", level, msg, describe(fields), o)
+		return observer.LoggedEntry{}
+	}
+	return matched[0]
+}
+
+// AssertNotLogged
+/**
+ * @Description: fail t when an entry matches, see Filter
+ * @receiver o
+ * @param t
+ * @param level
+ * @param msg
+ * @param fields
+ */
+func (o *Observer) AssertNotLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+	if matched := o.Filter(level, msg, fields...); len(matched) > 0 {
+		t.Errorf("unexpected %s entry %q, logged:\n%s", level, matched[0].Message, o)
+	}
+}
+
+// String one line per captured entry
+func (o *Observer) String() string {
+	var b strings.Builder
+	for _, e := range o.logs.All() {
+		fmt.Fprintf(&b, "\t%s %q %v\n", e.Level, e.Message, e.ContextMap())
+	}
+	return b.String()
+}
+
+// hasFields every field is in the context of e with the same encoded value
+func hasFields(e observer.LoggedEntry, fields []zap.Field) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	got := e.ContextMap()
+	for _, f := range fields {
+		v, ok := got[f.Key]
+		if !ok || !reflect.DeepEqual(v, encoded(f)) {
+			return false
+		}
+	}
+	return true
+}
+
+func encoded(f zap.Field) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
+
+func describe(fields []zap.Field) string {
+	if len(fields) == 0 {
+		return "any fields"
+	}
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = fmt.Sprintf("%s=%v", f.Key, encoded(f))
+	}
+	return strings.Join(parts, " ")
+}
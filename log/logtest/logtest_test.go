@@ -0,0 +1,67 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recorder keeps failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestObserver(t *testing.T) {
+	obs := New(t)
+	ctx := context.WithValue(context.Background(), mlog.RequestIdKey, "r1")
+	mlog.InfoCtx(ctx, "order paid", zap.Int("amount", 42))
+	mlog.Debug("cache miss")
+	mlog.Named("billing").Warn("retrying", zap.Error(fmt.Errorf("timeout")))
+
+	e := obs.AssertLogged(t, zapcore.InfoLevel, "paid", zap.Int("amount", 42), zap.String(mlog.SessionId.ToString(), "r1"))
+	if e.Message != "order paid" {
+		t.Errorf("match => %+v", e)
+	}
+	obs.AssertLogged(t, zapcore.DebugLevel, "cache")
+	obs.AssertLogged(t, zapcore.WarnLevel, "retrying", zap.Error(fmt.Errorf("timeout")))
+	obs.AssertNotLogged(t, zapcore.ErrorLevel, "")
+
+	rec := &recorder{TB: t}
+	obs.AssertLogged(rec, zapcore.InfoLevel, "paid", zap.Int("amount", 41))
+	obs.AssertLogged(rec, zapcore.ErrorLevel, "paid")
+	obs.AssertNotLogged(rec, zapcore.InfoLevel, "order")
+	if len(rec.errors) != 3 {
+		t.Errorf("failures => %q", rec.errors)
+	}
+
+	if n := len(obs.TakeAll()); n != 3 || len(obs.Entries()) != 0 {
+		t.Errorf("took %d, left %d", n, len(obs.Entries()))
+	}
+	mlog.SetLevel("info")
+	mlog.Debug("hidden")
+	obs.AssertNotLogged(t, zapcore.DebugLevel, "hidden")
+}
+
+func TestNew_RestoresLogger(t *testing.T) {
+	prev := mlog.GetLogger()
+	t.Run("observed", func(t *testing.T) {
+		New(t)
+		if mlog.GetLogger() == prev {
+			t.Error("observer not installed")
+		}
+	})
+	if mlog.GetLogger() != prev {
+		t.Error("previous logger not restored")
+	}
+}
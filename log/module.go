@@ -0,0 +1,152 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// _gModules module levels of every logger installed, kept across New, Reset and Reload
+var _gModules moduleLevels
+
+// moduleLevel level of one named logger, follows the sink levels until set
+type moduleLevel struct {
+	set   int32
+	level zap.AtomicLevel
+}
+
+func (m *moduleLevel) isSet() bool {
+	return atomic.LoadInt32(&m.set) == 1
+}
+
+func (m *moduleLevel) setLevel(lvl zapcore.Level) {
+	m.level.SetLevel(lvl)
+	atomic.StoreInt32(&m.set, 1)
+}
+
+// moduleEnabler module level when set, otherwise the sink level
+type moduleEnabler struct {
+	module *moduleLevel
+	base   zapcore.LevelEnabler
+}
+
+func (e moduleEnabler) Enabled(lvl zapcore.Level) bool {
+	if e.module.isSet() {
+		return e.module.level.Enabled(lvl)
+	}
+	return e.base.Enabled(lvl)
+}
+
+// Named
+/**
+ * @Description: creates a logger for a module whose level can be set on its own,
+ * through Config.Modules or SetModuleLevel. Until then it follows the global levels.
+ * The module level replaces Config.Level and SetLevel only, the console keeps
+ * StdLevel and sinks with a level of their own keep it. Module levels outlive
+ * New, Reset and Reload, Config.Modules sets those it names.
+ * The logger always writes to the sinks installed by the latest New or Reset.
+ * @param name e.g. billing
+ * @return *zap.Logger
+ */
+func Named(name string) *zap.Logger {
+	return zap.New(&namedCore{name: name}, zap.AddCaller()).Named(name)
+}
+
+// SetModuleLevel
+/**
+ * @Description: set the level of a named logger, an empty level follows the global levels again
+ * @param name
+ * @param levelStr
+ * @return error
+ */
+func SetModuleLevel(name, levelStr string) error {
+	ml := _gModules.get(name)
+	if levelStr == "" {
+		atomic.StoreInt32(&ml.set, 0)
+		return nil
+	}
+	l := new(Level)
+	if err := l.Unpack(levelStr); err != nil {
+		return err
+	}
+	ml.setLevel(l.zapLevel())
+	return nil
+}
+
+// GetModuleLevel
+/**
+ * @Description:
+ * @param name
+ * @return *Level
+ * @return bool false when the module follows the global levels
+ */
+func GetModuleLevel(name string) (*Level, bool) {
+	ml := _gModules.get(name)
+	if !ml.isSet() {
+		return nil, false
+	}
+	l := fromZapLevel(ml.level.Level())
+	return &l, true
+}
+
+// moduleCore core for name over the current sinks, the module level stands
+// in for Level only. A Core supplied without sinks is filtered as a whole.
+func (p *ZapProperties) moduleCore(name string) zapcore.Core {
+	ml := _gModules.get(name)
+	return p.newCore(func(base zapcore.LevelEnabler) zapcore.LevelEnabler {
+		if len(p.sinks) > 0 && base != zapcore.LevelEnabler(p.AtomicLevel) {
+			return base
+		}
+		return moduleEnabler{module: ml, base: base}
+	})
+}
+
+// namedCore resolves the module core against the installed properties,
+// so loggers created before New or kept across Reset stay valid.
+type namedCore struct {
+	name   string
+	fields []zapcore.Field
+	cache  atomic.Value // *namedCache
+}
+
+type namedCache struct {
+	props *ZapProperties
+	core  zapcore.Core
+}
+
+func (c *namedCore) current() zapcore.Core {
+	p := GetProps()
+	if cached, ok := c.cache.Load().(*namedCache); ok && cached.props == p {
+		return cached.core
+	}
+	core := p.moduleCore(c.name)
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	c.cache.Store(&namedCache{props: p, core: core})
+	return core
+}
+
+func (c *namedCore) Enabled(lvl zapcore.Level) bool {
+	return c.current().Enabled(lvl)
+}
+
+func (c *namedCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return &namedCore{name: c.name, fields: all}
+}
+
+func (c *namedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+func (c *namedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *namedCore) Sync() error {
+	return c.current().Sync()
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNamed_ModuleLevel(t *testing.T) {
+	// created before the logger is initialized
+	billing := Named("billing")
+	orders := Named("orders").With(zap.String("k", "v"))
+
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "error", Format: "json", Modules: map[string]string{"billing": "debug"}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+
+	billing.Debug("billing debug")
+	orders.Debug("orders hidden")
+	Debug("global hidden")
+	orders.Info("orders info")
+
+	if err := SetModuleLevel("orders", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	orders.Debug("orders debug")
+	if err := SetModuleLevel("billing", "error"); err != nil {
+		t.Fatal(err)
+	}
+	billing.Warn("billing hidden")
+	if err := SetModuleLevel("billing", ""); err != nil {
+		t.Fatal(err)
+	}
+	billing.Info("billing follows global")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("disabled entries written: %s", out)
+	}
+	for _, want := range []string{"billing debug", "orders info", "orders debug", `"k":"v"`, `"logger":"billing"`, "billing follows global"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in %s", want, out)
+		}
+	}
+	if lv, ok := GetModuleLevel("orders"); !ok || lv.String() != "debug" {
+		t.Errorf("GetModuleLevel => %v %v", lv, ok)
+	}
+	if err := SetModuleLevel("orders", "verbose"); err == nil {
+		t.Error("invalid module level accepted")
+	}
+}
+
+func TestNamed_ModuleLevelScope(t *testing.T) {
+	billing := Named("billing")
+	defer SetModuleLevel("billing", "") //nolint:errcheck
+	if err := SetModuleLevel("billing", "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	// set before the rebuild, kept by it
+	file, console := &bytes.Buffer{}, &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "info", Format: "json"}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.sinks[len(p.sinks)-1].out = zapcore.AddSync(console)
+	Reset(l, p)
+	defer New(nil)
+
+	billing.Debug("billing debug")
+	if !strings.Contains(file.String(), "billing debug") {
+		t.Errorf("file => %s", file.String())
+	}
+	if strings.Contains(console.String(), "billing debug") {
+		t.Errorf("console below StdLevel => %s", console.String())
+	}
+}
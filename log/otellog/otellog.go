@@ -0,0 +1,95 @@
+// Package otellog joins log lines written through the log package with
+// OpenTelemetry traces.
+package otellog
+
+import (
+	"context"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type tracer struct {
+	t trace.Tracer
+}
+
+// Tracer
+/**
+ * @Description: mlog.Tracer starting an OpenTelemetry server span per request,
+ * use with mlog.WithTracer
+ * @param t
+ * @return mlog.Tracer
+ */
+func Tracer(t trace.Tracer) mlog.Tracer {
+	return tracer{t: t}
+}
+
+// Start implements mlog.Tracer
+func (b tracer) Start(ctx context.Context, name string, remote mlog.TraceContext) (context.Context, mlog.TraceContext, func()) {
+	if remote.ParentID != "" {
+		if sc, ok := spanContext(remote); ok {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	ctx, span := b.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+	end := func() { span.End() }
+	sc := span.SpanContext()
+	// a no-op tracer hands back the remote span, keep the span id generated for us
+	if !sc.IsValid() || sc.SpanID().String() == remote.ParentID {
+		return ctx, remote, end
+	}
+	return ctx, fromSpanContext(sc, remote.ParentID), end
+}
+
+// RegisterExtractor
+/**
+ * @Description: replace the built-in "trace" ctx extractor so log lines carry
+ * the innermost OpenTelemetry span of ctx, which may be a child span started
+ * by the handler, falling back to the trace stored by GinLogger
+ */
+func RegisterExtractor() {
+	mlog.RegisterCtxExtractor("trace", func(ctx context.Context) []zap.Field {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			return []zap.Field{
+				zap.String(mlog.TraceId.ToString(), sc.TraceID().String()),
+				zap.String(mlog.SpanId.ToString(), sc.SpanID().String()),
+			}
+		}
+		tc, ok := mlog.TraceFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return []zap.Field{zap.String(mlog.TraceId.ToString(), tc.TraceID), zap.String(mlog.SpanId.ToString(), tc.SpanID)}
+	})
+}
+
+func spanContext(tc mlog.TraceContext) (trace.SpanContext, bool) {
+	traceID, err := trace.TraceIDFromHex(tc.TraceID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(tc.ParentID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	// an invalid tracestate is dropped, the trace itself still continues
+	state, _ := trace.ParseTraceState(tc.State)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(tc.Flags),
+		TraceState: state,
+		Remote:     true,
+	}), true
+}
+
+func fromSpanContext(sc trace.SpanContext, parentID string) mlog.TraceContext {
+	return mlog.TraceContext{
+		TraceID:  sc.TraceID().String(),
+		SpanID:   sc.SpanID().String(),
+		ParentID: parentID,
+		Flags:    byte(sc.TraceFlags()),
+		State:    sc.TraceState().String(),
+	}
+}
@@ -0,0 +1,71 @@
+package otellog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mlog "github.com/IvanWhisper/michelangelo/log"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTracer_GinLogger(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	RegisterExtractor()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mlog.GinLogger(mlog.WithTracer(Tracer(tp.Tracer("test")))))
+	var child trace.SpanContext
+	var fields map[string]interface{}
+	r.GET("/users/:id", func(c *gin.Context) {
+		ctx, span := tp.Tracer("test").Start(c.Request.Context(), "db")
+		child = span.SpanContext()
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range mlog.Ctx2Fields(ctx) {
+			f.AddTo(enc)
+		}
+		fields = enc.Fields
+		span.End()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(mlog.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans => %d", len(spans))
+	}
+	server := spans[1]
+	if server.Name() != "GET /users/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span => %s %v", server.Name(), server.SpanKind())
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" || server.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span parent => %v", server.Parent())
+	}
+	out, err := mlog.ParseTraceparent(resp.Header().Get(mlog.TraceparentHeader))
+	if err != nil || out.ParentID != server.SpanContext().SpanID().String() {
+		t.Errorf("response traceparent => %q", resp.Header().Get(mlog.TraceparentHeader))
+	}
+	if fields[mlog.SpanId.ToString()] != child.SpanID().String() {
+		t.Errorf("log fields => %v, want child span %s", fields, child.SpanID())
+	}
+}
+
+func TestTracer_Noop(t *testing.T) {
+	remote := mlog.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "1111111111111111", ParentID: "00f067aa0ba902b7", Flags: 1}
+	_, tc, end := Tracer(trace.NewNoopTracerProvider().Tracer("")).Start(context.Background(), "x", remote)
+	end()
+	if tc != remote {
+		t.Errorf("noop tracer => %+v", tc)
+	}
+}
@@ -1,11 +1,141 @@
 package log
 
 import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type ZapProperties struct {
-	Core   zapcore.Core
-	Syncer zapcore.WriteSyncer
-	Level  *Level
+	Core        zapcore.Core
+	Syncer      zapcore.WriteSyncer
+	Level       *Level          // file core level the logger was built with, GetLevel reports the running one
+	AtomicLevel zap.AtomicLevel // file core level, changed by SetLevel
+	StdLevel    zap.AtomicLevel // console core level, changed by SetStdLevel
+
+	sinks    []sink                   // outputs Core is built from, empty when Core was supplied by the caller
+	live     *coreSlot                // core the built logger writes through, kept by Reload
+	stdBuilt *Level                   // console level the logger was built with
+	modules  map[string]zapcore.Level // Config.Modules, set in the global module levels by Reset
+	redactor *Redactor                // masks every sink when not nil
+	sampling *SamplingConfig
+	limiter  *RateLimiter
+	buffers  *requestBuffers                // per-request buffering, nil when off
+	debug    atomic.Value                   // *debugCache, logger of contexts raised to debug
+	closers  []io.Closer                    // writers opened for this logger, closed by Close
+	writers  map[string]zapcore.WriteSyncer // writers by writerKey, taken over by Reload
+	crash    *crashReporter                 // writes HandleCrash reports, nil when off
+	exitCode int                            // of Fatal, 0 is 1
+	crashed  uint32                         // set by the first HandleCrash, which writes the report
+}
+
+// sink one output with its own level
+type sink struct {
+	encoder zapcore.Encoder
+	out     zapcore.WriteSyncer
+	level   zapcore.LevelEnabler
+}
+
+// newCore
+/**
+ * @Description: tee of all sinks, enabler wraps each sink level when not nil.
+ * Sampling and rate limiting apply on top of the tee, request buffering on top of those.
+ * Without sinks the supplied Core is filtered instead, which can only raise its level.
+ * @receiver p
+ * @param enabler
+ * @return zapcore.Core
+ */
+func (p *ZapProperties) newCore(enabler func(base zapcore.LevelEnabler) zapcore.LevelEnabler) zapcore.Core {
+	if len(p.sinks) == 0 {
+		if enabler == nil || p.Core == nil {
+			return p.Core
+		}
+		return &filterCore{Core: p.Core, enab: enabler(p.Core)}
+	}
+	cores := make([]zapcore.Core, 0, len(p.sinks))
+	for _, s := range p.sinks {
+		enab := s.level
+		if enabler != nil {
+			enab = enabler(enab)
+		}
+		core := newSinkCore(s.encoder, s.out, enab)
+		if p.redactor != nil {
+			core = NewRedactCore(core, p.redactor)
+		}
+		cores = append(cores, core)
+	}
+	core := zapcore.NewTee(cores...)
+	if p.sampling != nil {
+		core = NewSamplerCore(core, *p.sampling)
+	}
+	if p.limiter != nil {
+		core = NewRateLimitCore(core, p.limiter)
+	}
+	if p.buffers != nil {
+		core = newTailCore(core, p.bufferCore(), p.buffers)
+	}
+	return core
+}
+
+// bufferCore sinks following Level with every level enabled, buffered request entries are written there
+func (p *ZapProperties) bufferCore() zapcore.Core {
+	cores := make([]zapcore.Core, 0, len(p.sinks))
+	for _, s := range p.sinks {
+		if s.level != zapcore.LevelEnabler(p.AtomicLevel) {
+			continue
+		}
+		core := newSinkCore(s.encoder, s.out, zapcore.DebugLevel)
+		if p.redactor != nil {
+			core = NewRedactCore(core, p.redactor)
+		}
+		cores = append(cores, core)
+	}
+	return zapcore.NewTee(cores...)
+}
+
+// filterCore
+/**
+ * @Description: applies an extra level check in front of a core
+ */
+type filterCore struct {
+	zapcore.Core
+	enab zapcore.LevelEnabler
+}
+
+func (c *filterCore) Enabled(lvl zapcore.Level) bool {
+	return c.enab.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{Core: c.Core.With(fields), enab: c.enab}
+}
+
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enab.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// moduleLevels per-module levels, created on first use
+type moduleLevels struct {
+	mu     sync.Mutex
+	levels map[string]*moduleLevel
+}
+
+func (m *moduleLevels) get(name string) *moduleLevel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.levels == nil {
+		m.levels = make(map[string]*moduleLevel)
+	}
+	ml, ok := m.levels[name]
+	if !ok {
+		ml = &moduleLevel{level: zap.NewAtomicLevel()}
+		m.levels[name] = ml
+	}
+	return ml
 }
@@ -0,0 +1,430 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces every masked value
+const redactedValue = "***"
+
+// Built-in RedactConfig.Patterns
+const (
+	RedactCard  = "card"
+	RedactPhone = "phone"
+	RedactEmail = "email"
+)
+
+var (
+	defaultRedactFields   = []string{"password", "passwd", "pwd", "token", "accessToken", "refreshToken", "secret", "apiKey", "authorization"}
+	defaultRedactPatterns = []string{RedactCard, RedactPhone, RedactEmail}
+
+	builtinRedactPatterns = map[string]string{
+		RedactCard:  `\b(?:\d[ -]?){12,18}\d\b`,
+		RedactPhone: `(?:\+?86[- ]?)?\b1[3-9]\d{9}\b|\+\d{1,3}[- ]\d{2,4}[- ]\d{3,4}[- ]\d{3,4}\b`,
+		RedactEmail: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	}
+)
+
+// RedactConfig
+/**
+ * @Description: serializes redaction related config in toml/json/yaml.
+ * Fields are masked by name at any depth, Patterns inside every string value
+ * and the message, QueryParams inside query strings and URLs.
+ * SQL args logged by OrmLoggerAdapter and SQLDriver are also masked when
+ * bound to a column named in Fields, on top of SQLLogConfig.MaskColumns.
+ */
+type RedactConfig struct {
+	Enable      bool     `toml:"enable" json:"enable" yaml:"enable"`
+	Fields      []string `toml:"fields" json:"fields" yaml:"fields"`                // Field names, case insensitive. Default password, token, secret and similar.
+	Patterns    []string `toml:"patterns" json:"patterns" yaml:"patterns"`          // card, phone, email or a regular expression. Default card, phone and email.
+	QueryParams []string `toml:"queryParams" json:"queryParams" yaml:"queryParams"` // Query parameter names, default the same as Fields.
+}
+
+// Redactor
+/**
+ * @Description: masks sensitive values of log entries, see RedactConfig
+ */
+type Redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+	card     *regexp.Regexp
+	query    *regexp.Regexp
+}
+
+// NewRedactor
+/**
+ * @Description: compile cfg, Enable is not checked
+ * @param cfg
+ * @return *Redactor
+ * @return error
+ */
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = defaultRedactFields
+	}
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultRedactPatterns
+	}
+	params := cfg.QueryParams
+	if len(params) == 0 {
+		params = fields
+	}
+
+	r := &Redactor{fields: make(map[string]bool, len(fields))}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	for _, p := range patterns {
+		expr, builtin := builtinRedactPatterns[p]
+		if !builtin {
+			expr = p
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("log.redact.patterns: %w", err)
+		}
+		if p == RedactCard {
+			r.card = re
+			continue
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	if len(params) > 0 {
+		names := make([]string, 0, len(params))
+		for _, p := range params {
+			names = append(names, regexp.QuoteMeta(p))
+		}
+		r.query = regexp.MustCompile(`(?i)((?:^|[?&;])(?:` + strings.Join(names, "|") + `)=)[^&#\s"]*`)
+	}
+	return r, nil
+}
+
+// String mask patterns and query parameters in s
+func (r *Redactor) String(s string) string {
+	if r.query != nil {
+		s = r.query.ReplaceAllString(s, "${1}"+redactedValue)
+	}
+	if r.card != nil {
+		s = r.card.ReplaceAllStringFunc(s, func(m string) string {
+			if luhn(m) {
+				return redactedValue
+			}
+			return m
+		})
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// Sensitive reports whether values of the field name are masked
+func (r *Redactor) Sensitive(name string) bool {
+	return r.fields[strings.ToLower(name)]
+}
+
+// Fields
+/**
+ * @Description: redacted copy of fields, nested objects and arrays are
+ * masked while they are encoded
+ * @receiver r
+ * @param fields
+ * @return []zapcore.Field
+ */
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.field(f)
+	}
+	return out
+}
+
+func (r *Redactor) field(f zapcore.Field) zapcore.Field {
+	if r.Sensitive(f.Key) && f.Type != zapcore.SkipType && f.Type != zapcore.NamespaceType {
+		return zap.String(f.Key, redactedValue)
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.String(f.String)
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, r.String(string(f.Interface.([]byte))))
+	case zapcore.StringerType:
+		return zap.String(f.Key, r.String(stringOf(f.Interface)))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			if masked := r.String(err.Error()); masked != err.Error() {
+				return zap.String(f.Key, masked)
+			}
+		}
+	case zapcore.ObjectMarshalerType:
+		m := f.Interface.(zapcore.ObjectMarshaler)
+		return zap.Object(f.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			return m.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: r})
+		}))
+	case zapcore.ArrayMarshalerType:
+		m := f.Interface.(zapcore.ArrayMarshaler)
+		return zap.Array(f.Key, zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+			return m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: r})
+		}))
+	case zapcore.ReflectType:
+		return zap.Reflect(f.Key, r.reflected(f.Interface))
+	}
+	return f
+}
+
+// reflected mask a value logged through zap.Any, walking its JSON form
+func (r *Redactor) reflected(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return v
+	}
+	return r.value(generic)
+}
+
+func (r *Redactor) value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return r.String(t)
+	case map[string]interface{}:
+		for k, child := range t {
+			if r.Sensitive(k) {
+				t[k] = redactedValue
+			} else {
+				t[k] = r.value(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = r.value(child)
+		}
+	}
+	return v
+}
+
+// stringOf call String, a panicking Stringer is reported the way zap does
+func stringOf(v interface{}) (s string) {
+	defer func() {
+		if err := recover(); err != nil {
+			s = fmt.Sprintf("PANIC=%v", err)
+		}
+	}()
+	return v.(fmt.Stringer).String()
+}
+
+// luhn checksum of the digits in s, tells card numbers from other digit runs
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// redactCore
+/**
+ * @Description: masks entries before they reach the encoder of one sink.
+ * Sinks are wrapped one by one because a tee writes to all its cores.
+ */
+type redactCore struct {
+	zapcore.Core
+	r *Redactor
+}
+
+// NewRedactCore
+/**
+ * @Description: wrap a core writing to a single output
+ * @param core
+ * @param r
+ * @return zapcore.Core
+ */
+func NewRedactCore(core zapcore.Core, r *Redactor) zapcore.Core {
+	return &redactCore{Core: core, r: r}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.Fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.String(ent.Message)
+	return c.Core.Write(ent, c.r.Fields(fields))
+}
+
+// redactObjectEncoder masks values added by an ObjectMarshaler
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *Redactor
+}
+
+func (e *redactObjectEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		return m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: e.r})
+	}))
+}
+
+func (e *redactObjectEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		return m.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: e.r})
+	}))
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, v interface{}) error {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.reflected(v))
+}
+
+func (e *redactObjectEncoder) AddString(key, value string) {
+	if e.r.Sensitive(key) {
+		value = redactedValue
+	}
+	e.ObjectEncoder.AddString(key, e.r.String(value))
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, value []byte) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddBinary(key, value)
+}
+
+func (e *redactObjectEncoder) AddBool(key string, value bool) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddBool(key, value)
+}
+
+func (e *redactObjectEncoder) AddDuration(key string, value time.Duration) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddDuration(key, value)
+}
+
+func (e *redactObjectEncoder) AddTime(key string, value time.Time) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddTime(key, value)
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, value float64) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddFloat64(key, value)
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, value int64) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddInt64(key, value)
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, value uint64) {
+	if e.r.Sensitive(key) {
+		e.ObjectEncoder.AddString(key, redactedValue)
+		return
+	}
+	e.ObjectEncoder.AddUint64(key, value)
+}
+
+// the narrower number types funnel into the 64 bit ones
+
+func (e *redactObjectEncoder) AddFloat32(key string, value float32) {
+	e.AddFloat64(key, float64(value))
+}
+
+func (e *redactObjectEncoder) AddInt(key string, value int)     { e.AddInt64(key, int64(value)) }
+func (e *redactObjectEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+func (e *redactObjectEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+func (e *redactObjectEncoder) AddInt8(key string, value int8)   { e.AddInt64(key, int64(value)) }
+
+func (e *redactObjectEncoder) AddUint(key string, value uint)       { e.AddUint64(key, uint64(value)) }
+func (e *redactObjectEncoder) AddUint32(key string, value uint32)   { e.AddUint64(key, uint64(value)) }
+func (e *redactObjectEncoder) AddUint16(key string, value uint16)   { e.AddUint64(key, uint64(value)) }
+func (e *redactObjectEncoder) AddUint8(key string, value uint8)     { e.AddUint64(key, uint64(value)) }
+func (e *redactObjectEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+// redactArrayEncoder masks values appended by an ArrayMarshaler
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *Redactor
+}
+
+func (e *redactArrayEncoder) AppendArray(m zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		return m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: e.r})
+	}))
+}
+
+func (e *redactArrayEncoder) AppendObject(m zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		return m.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: e.r})
+	}))
+}
+
+func (e *redactArrayEncoder) AppendReflected(v interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.reflected(v))
+}
+
+func (e *redactArrayEncoder) AppendString(v string) {
+	e.ArrayEncoder.AppendString(e.r.String(v))
+}
+
+func (e *redactArrayEncoder) AppendByteString(v []byte) {
+	e.ArrayEncoder.AppendString(e.r.String(string(v)))
+}
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("password", c.Password)
+	return enc.AddArray("contacts", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		enc.AppendString("bob@example.com")
+		return nil
+	}))
+}
+
+func TestRedactor_String(t *testing.T) {
+	r, err := NewRedactor(RedactConfig{Enable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"card 4111 1111 1111 1111 paid":      "card *** paid",
+		"order 4111111111111112 not a card":  "order 4111111111111112 not a card",
+		"call 13812345678 now":               "call *** now",
+		"mail bob@example.com":               "mail ***",
+		"GET /login?user=bob&token=abc&x=1":  "GET /login?user=bob&token=***&x=1",
+		"Password=hunter2&next=/home":        "Password=***&next=/home",
+		"nothing to see, id 1234 code 98765": "nothing to see, id 1234 code 98765",
+	}
+	for in, want := range cases {
+		if got := r.String(in); got != want {
+			t.Errorf("%q => %q!=%q", in, got, want)
+		}
+	}
+	if _, err := NewRedactor(RedactConfig{Patterns: []string{"("}}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+func TestInitLogger_Redact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", Redact: RedactConfig{Enable: true, Fields: []string{"password", "token"}}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+
+	With(zap.String("token", "with-secret")).Info("login from alice@example.com",
+		zap.String(QueryText.ToString(), "a=1&token=query-secret"),
+		zap.Int("password", 1234),
+		zap.Object("creds", credentials{User: "bob", Password: "obj-secret"}),
+		zap.Any("raw", map[string]interface{}{"nested": map[string]string{"password": "map-secret"}}),
+		zap.Error(errors.New("card 4111111111111111 declined")),
+	)
+	out := buf.String()
+	for _, secret := range []string{"with-secret", "query-secret", "1234", "obj-secret", "map-secret", "alice@example.com", "bob@example.com", "4111111111111111"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s leaked: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"user":"bob"`) {
+		t.Errorf("unrelated field masked: %s", out)
+	}
+}
+
+func TestConfig_ValidateRedact(t *testing.T) {
+	cfg := &Config{Redact: RedactConfig{Enable: true, Patterns: []string{RedactEmail, `[`}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("invalid redact pattern accepted")
+	}
+}
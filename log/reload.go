@@ -0,0 +1,132 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	_gReloadMu sync.Mutex
+	// reloadGrace replaced writers stay open this long for entries checked before the swap
+	reloadGrace = time.Second
+)
+
+// Reload
+/**
+ * @Description: rebuild the global logger from cfg, e.g. after the config
+ * file changed. Levels, format, sinks and rotation all follow cfg.
+ * Loggers derived before the reload, package loggers and Named ones included,
+ * write through the new sinks at once. Writers whose config is unchanged are
+ * handed over and stay open, the others are synced at once and closed after
+ * a grace period. Levels set at runtime are kept unless cfg changes them,
+ * module levels are only set for the modules cfg names.
+ * An invalid cfg is logged and rejected, the running logger stays.
+ * @param cfg
+ * @return error
+ */
+func Reload(cfg *Config) error {
+	_gReloadMu.Lock()
+	defer _gReloadMu.Unlock()
+	if err := cfg.Validate(); err != nil {
+		GetLogger().Error("log: reload rejected, keeping the running config", zap.Error(err))
+		return err
+	}
+	old := GetProps()
+	l, p, err := buildLogger(cfg, old)
+	if err != nil {
+		GetLogger().Error("log: reload rejected, keeping the running config", zap.Error(err))
+		return err
+	}
+	Reset(l, p)
+	if old.Core != nil {
+		_ = old.Core.Sync()
+	}
+	closeOld := func() {
+		if err := old.closeExcept(p); err != nil {
+			GetLogger().Warn("log: closing writers replaced by reload", zap.Error(err))
+		}
+	}
+	if reloadGrace > 0 {
+		time.AfterFunc(reloadGrace, closeOld)
+	} else {
+		closeOld()
+	}
+	GetLogger().Info("log: config reloaded", zap.String("level", cfg.Level), zap.String("format", cfg.Format))
+	return nil
+}
+
+// coreSlot core installed for a logger built by New, Reload puts the new one in
+type coreSlot struct {
+	v atomic.Value // *coreBox
+}
+
+// coreBox one installed core, compared by pointer
+type coreBox struct {
+	core zapcore.Core
+}
+
+func newCoreSlot(core zapcore.Core) *coreSlot {
+	s := &coreSlot{}
+	s.store(core)
+	return s
+}
+
+func (s *coreSlot) load() *coreBox {
+	return s.v.Load().(*coreBox)
+}
+
+func (s *coreSlot) store(core zapcore.Core) {
+	s.v.Store(&coreBox{core: core})
+}
+
+// liveCore writes to the core in slot, children built by With follow too
+type liveCore struct {
+	slot   *coreSlot
+	fields []zapcore.Field
+	cache  atomic.Value // *liveCache
+}
+
+type liveCache struct {
+	box  *coreBox
+	core zapcore.Core
+}
+
+func (c *liveCore) current() zapcore.Core {
+	box := c.slot.load()
+	if len(c.fields) == 0 {
+		return box.core
+	}
+	if cached, ok := c.cache.Load().(*liveCache); ok && cached.box == box {
+		return cached.core
+	}
+	core := box.core.With(c.fields)
+	c.cache.Store(&liveCache{box: box, core: core})
+	return core
+}
+
+func (c *liveCore) Enabled(lvl zapcore.Level) bool {
+	return c.current().Enabled(lvl)
+}
+
+func (c *liveCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return &liveCore{slot: c.slot, fields: all}
+}
+
+func (c *liveCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+func (c *liveCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *liveCore) Sync() error {
+	return c.current().Sync()
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func readLog(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	New(&Config{Level: "info", StdLevel: "critical", File: FileLogConfig{FileDir: dir, FileName: "old.log"}})
+	t.Cleanup(func() { New(nil) })
+	held := GetLogger().With(zap.String("held", "yes")) // e.g. a child logger built before the reload
+	oldFile := GetProps().writers[writerKey(SinkFile, FileLogConfig{FileDir: dir, FileName: "old.log"})].(*RotateWriter)
+
+	grace := reloadGrace
+	reloadGrace = 50 * time.Millisecond
+	defer func() { reloadGrace = grace }()
+
+	cfg := &Config{Level: "debug", StdLevel: "critical", Format: FormatJSON, File: FileLogConfig{FileDir: dir, FileName: "new.log"}}
+	if err := Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	Debug("after reload")
+	held.Debug("held")
+	Named("billing").Debug("named")
+	_ = Sync()
+	out := readLog(t, filepath.Join(dir, "new.log"))
+	for _, want := range []string{`"msg":"after reload"`, `"held":"yes"`, `"logger":"billing"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("new.log lacks %s => %s", want, out)
+		}
+	}
+	if out := readLog(t, filepath.Join(dir, "old.log")); strings.Contains(out, "held") {
+		t.Errorf("held logger kept the old sinks => %s", out)
+	}
+
+	// rejected configs keep the running logger and are logged
+	props := GetProps()
+	for _, bad := range []*Config{
+		{Level: "verbose"},
+		{Level: "info", Sinks: []SinkConfig{{Type: SinkSyslog, Syslog: SyslogConfig{Address: filepath.Join(dir, "missing.sock")}}}},
+	} {
+		if err := Reload(bad); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+	if GetProps() != props {
+		t.Error("running logger replaced by a rejected config")
+	}
+	_ = Sync()
+	if out := readLog(t, filepath.Join(dir, "new.log")); strings.Count(out, "reload rejected") != 2 {
+		t.Errorf("rejections not logged => %s", out)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	oldFile.mu.Lock()
+	if !oldFile.closed {
+		t.Error("old writer still open after the grace period")
+	}
+	oldFile.mu.Unlock()
+}
+
+func TestReload_KeepsWritersAndLevels(t *testing.T) {
+	dir := t.TempDir()
+	file := FileLogConfig{FileDir: dir, FileName: "app.log"}
+	New(&Config{Level: "info", StdLevel: "critical", Format: FormatJSON, File: file})
+	t.Cleanup(func() { New(nil) })
+	key := writerKey(SinkFile, file)
+	w := GetProps().writers[key]
+	if err := SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+
+	grace := reloadGrace
+	reloadGrace = 0
+	defer func() { reloadGrace = grace }()
+
+	// same file and level, only the format changes
+	if err := Reload(&Config{Level: "info", StdLevel: "critical", Format: FormatLogfmt, File: file}); err != nil {
+		t.Fatal(err)
+	}
+	if GetProps().writers[key] != w {
+		t.Error("unchanged file writer replaced")
+	}
+	if w.(*RotateWriter).closed {
+		t.Error("unchanged file writer closed")
+	}
+	if *GetLevel() != ErrorLevel {
+		t.Errorf("runtime level => %v", *GetLevel())
+	}
+	Error("kept")
+	_ = Sync()
+	if out := readLog(t, filepath.Join(dir, "app.log")); !strings.Contains(out, "msg=kept") {
+		t.Errorf("app.log => %s", out)
+	}
+
+	// a level changed in the config wins over the runtime one
+	if err := Reload(&Config{Level: "warning", StdLevel: "critical", Format: FormatLogfmt, File: file}); err != nil {
+		t.Fatal(err)
+	}
+	if *GetLevel() != WarnLevel {
+		t.Errorf("config level => %v", *GetLevel())
+	}
+}
@@ -0,0 +1,378 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation modes of FileLogConfig.Rotation
+const (
+	RotateByTime = "time"
+	RotateBySize = "size"
+	RotateByBoth = "both"
+)
+
+const (
+	megabyte       = 1024 * 1024
+	compressSuffix = ".gz"
+	// backupTimeFormat suffix of rotated files, same layout rotatelogs used
+	backupTimeFormat = "2006010215"
+)
+
+// RotateWriter
+/**
+ * @Description: file writer rotating by time, size or both. Rotated files are
+ * optionally gzip compressed, then pruned by MaxBackups, MaxDays and MaxTotalSize.
+ */
+type RotateWriter struct {
+	filename  string
+	bySize    bool
+	byTime    bool
+	maxBytes  int64
+	period    time.Duration
+	maxAge    time.Duration
+	maxBackup int
+	maxTotal  int64
+	compress  bool
+	now       func() time.Time
+
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	periodStart time.Time
+	closed      bool
+
+	millOnce sync.Once
+	millCh   chan struct{}
+	millWg   sync.WaitGroup
+}
+
+// NewRotateWriter
+/**
+ * @Description: open the log file described by cfg, creating its directory
+ * @param cfg
+ * @return *RotateWriter
+ * @return error
+ */
+func NewRotateWriter(cfg *FileLogConfig) (*RotateWriter, error) {
+	filename, err := initFileLogName(cfg)
+	if err != nil {
+		return nil, err
+	}
+	w := &RotateWriter{
+		filename:  filename,
+		maxBytes:  int64(cfg.MaxSize) * megabyte,
+		period:    time.Duration(cfg.RotationHours) * time.Hour,
+		maxAge:    time.Duration(cfg.MaxDays) * 24 * time.Hour,
+		maxBackup: cfg.MaxBackups,
+		maxTotal:  int64(cfg.MaxTotalSize) * megabyte,
+		compress:  cfg.Compress,
+		now:       time.Now,
+	}
+	if w.period <= 0 {
+		w.period = 24 * time.Hour
+	}
+	switch cfg.Rotation {
+	case RotateByTime:
+		w.byTime = true
+	case RotateBySize:
+		w.bySize = true
+	case RotateByBoth:
+		w.byTime, w.bySize = true, true
+	default:
+		// time based as before, plus size when MaxSize is set
+		w.byTime, w.bySize = true, cfg.MaxSize > 0
+	}
+	if w.bySize && w.maxBytes <= 0 {
+		return nil, fmt.Errorf("log: rotation by size needs maxSize")
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close the current file and wait for pending compression and cleanup,
+// later writes fail with os.ErrClosed
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	millCh := w.millCh
+	w.mu.Unlock()
+	w.millWg.Wait()
+	if millCh != nil {
+		// mill only runs under mu while open, nothing sends any more
+		close(millCh)
+	}
+	return err
+}
+
+// Rotate force a rotation
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+func (w *RotateWriter) periodOf(t time.Time) time.Time {
+	if w.period%(24*time.Hour) == 0 {
+		// align days on local midnight
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(w.period)
+}
+
+func (w *RotateWriter) shouldRotate(n int64) bool {
+	if w.bySize && w.size > 0 && w.size+n > w.maxBytes {
+		return true
+	}
+	if w.byTime && !w.periodOf(w.now()).Equal(w.periodStart) {
+		return w.size > 0
+	}
+	return false
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.periodStart = w.periodOf(w.now())
+	if w.size > 0 {
+		// an existing file belongs to the period it was last written in
+		w.periodStart = w.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+func (w *RotateWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	stamp := w.now()
+	if w.byTime && !w.bySize {
+		stamp = w.periodStart
+	}
+	if _, err := os.Stat(w.filename); err == nil {
+		if err := os.Rename(w.filename, w.backupName(stamp)); err != nil {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.mill()
+	return nil
+}
+
+// backupName first free name of filename.YYYYMMDDHH[.n]
+func (w *RotateWriter) backupName(t time.Time) string {
+	base := w.filename + "." + t.Format(backupTimeFormat)
+	name := base
+	for i := 1; ; i++ {
+		_, err := os.Stat(name)
+		_, errGz := os.Stat(name + compressSuffix)
+		if os.IsNotExist(err) && os.IsNotExist(errGz) {
+			return name
+		}
+		name = fmt.Sprintf("%s.%d", base, i)
+	}
+}
+
+// mill compress and prune rotated files in the background, one run at a time
+func (w *RotateWriter) mill() {
+	w.millOnce.Do(func() {
+		w.millCh = make(chan struct{}, 1)
+		go func() {
+			for range w.millCh {
+				w.millRun()
+				w.millWg.Done()
+			}
+		}()
+	})
+	w.millWg.Add(1)
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+		// a run is already queued and will see this backup too
+		w.millWg.Done()
+	}
+}
+
+type backupFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (w *RotateWriter) millRun() {
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+	if w.compress {
+		for i, b := range backups {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if gz, err := compressFile(b.path); err == nil {
+				backups[i] = gz
+			}
+		}
+	}
+
+	// newest first
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+	var total int64
+	w.mu.Lock()
+	total = w.size
+	w.mu.Unlock()
+	cutoff := w.now().Add(-w.maxAge)
+	for i, b := range backups {
+		total += b.size
+		remove := (w.maxBackup > 0 && i >= w.maxBackup) ||
+			(w.maxAge > 0 && b.modTime.Before(cutoff)) ||
+			(w.maxTotal > 0 && total > w.maxTotal)
+		if remove {
+			_ = os.Remove(b.path)
+			total -= b.size
+		}
+	}
+}
+
+func (w *RotateWriter) backups() ([]backupFile, error) {
+	dir, prefix := filepath.Dir(w.filename), filepath.Base(w.filename)+"."
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]backupFile, 0)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) || !isBackupSuffix(e.Name()[len(prefix):]) {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, e.Name()), size: e.Size(), modTime: e.ModTime()})
+	}
+	return backups, nil
+}
+
+// isBackupSuffix suffix is YYYYMMDDHH[.n][.gz] as written by backupName and compressFile
+func isBackupSuffix(suffix string) bool {
+	suffix = strings.TrimSuffix(suffix, compressSuffix)
+	parts := strings.SplitN(suffix, ".", 2)
+	if _, err := time.ParseInLocation(backupTimeFormat, parts[0], time.Local); err != nil || len(parts[0]) != len(backupTimeFormat) {
+		return false
+	}
+	if len(parts) == 2 {
+		n, err := strconv.Atoi(parts[1])
+		return err == nil && n > 0 && strconv.Itoa(n) == parts[1]
+	}
+	return true
+}
+
+// compressFile gzip src to src.gz keeping its mod time, then remove src
+func compressFile(src string) (backupFile, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return backupFile{}, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return backupFile{}, err
+	}
+	dst := src + compressSuffix
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return backupFile{}, err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return backupFile{}, err
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	_ = in.Close()
+	if err := os.Remove(src); err != nil {
+		return backupFile{}, err
+	}
+	outInfo, err := os.Stat(dst)
+	if err != nil {
+		return backupFile{}, err
+	}
+	return backupFile{path: dst, size: outInfo.Size(), modTime: info.ModTime()}, nil
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRotateWriter_SizeCompressBackups(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotateWriter(&FileLogConfig{FileDir: dir, FileName: "app.log", MaxSize: 1, Rotation: RotateBySize, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxBytes = 10
+	clock := time.Date(2021, 5, 1, 10, 0, 0, 0, time.Local)
+	w.now = func() time.Time { return clock }
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := listDir(t, dir)
+	var gz []string
+	for _, name := range names {
+		if strings.HasSuffix(name, compressSuffix) {
+			gz = append(gz, name)
+		} else if name != "app.log" {
+			t.Errorf("uncompressed backup left: %s", name)
+		}
+	}
+	if len(gz) != 2 {
+		t.Fatalf("backups => %v, want 2 compressed", names)
+	}
+	f, err := os.Open(filepath.Join(dir, gz[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(r)
+	if string(content) != "0123456789" {
+		t.Errorf("compressed content => %q", content)
+	}
+}
+
+func TestRotateWriter_Time(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotateWriter(&FileLogConfig{FileDir: dir, FileName: "app.log", Rotation: RotateByTime, RotationHours: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2021, 5, 1, 10, 30, 0, 0, time.Local)
+	w.now = func() time.Time { return clock }
+	w.periodStart = w.periodOf(clock)
+
+	_, _ = w.Write([]byte("first\n"))
+	_, _ = w.Write([]byte("first again\n"))
+	clock = clock.Add(time.Hour)
+	_, _ = w.Write([]byte("second\n"))
+	_ = w.Close()
+
+	backup := filepath.Join(dir, "app.log."+time.Date(2021, 5, 1, 10, 0, 0, 0, time.Local).Format(backupTimeFormat))
+	old, err := ioutil.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("backup missing: %v %v", err, listDir(t, dir))
+	}
+	if string(old) != "first\nfirst again\n" {
+		t.Errorf("backup => %q", old)
+	}
+	current, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if string(current) != "second\n" {
+		t.Errorf("current => %q", current)
+	}
+}
+
+func TestRotateWriter_MaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewRotateWriter(&FileLogConfig{FileDir: dir, FileName: "app.log", MaxSize: 1, Rotation: RotateBySize, MaxTotalSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxBytes = 100
+	w.maxTotal = 250
+	clock := time.Date(2021, 5, 1, 10, 0, 0, 0, time.Local)
+	w.now = func() time.Time { return clock }
+	chunk := bytes.Repeat([]byte("x"), 100)
+	for i := 0; i < 6; i++ {
+		_, _ = w.Write(chunk)
+		// distinct mod times so pruning order is stable
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = w.Close()
+
+	var total int64
+	for _, name := range listDir(t, dir) {
+		info, _ := os.Stat(filepath.Join(dir, name))
+		total += info.Size()
+	}
+	if total > 250 {
+		t.Errorf("total size => %d > 250 (%v)", total, listDir(t, dir))
+	}
+}
+
+func TestRotateWriter_KeepsUnrelatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.log", "app.error.log", "app.pid", "app.2021050110x", "app.2021050110.01"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("keep"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := NewRotateWriter(&FileLogConfig{FileDir: dir, FileName: "app", MaxSize: 1, Rotation: RotateBySize, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxBytes = 10
+	for i := 0; i < 4; i++ {
+		_, _ = w.Write([]byte("0123456789"))
+	}
+	_ = w.Close()
+	names := strings.Join(listDir(t, dir), " ")
+	for _, name := range []string{"app.log", "app.error.log", "app.pid", "app.2021050110x", "app.2021050110.01"} {
+		if !strings.Contains(" "+names+" ", " "+name+" ") {
+			t.Errorf("%s removed, left %s", name, names)
+		}
+	}
+	if !isBackupSuffix("2021050110.2.gz") || isBackupSuffix("log") || isBackupSuffix("2021050110.0") {
+		t.Error("isBackupSuffix")
+	}
+}
+
+func TestRotateWriter_WriteAfterClose(t *testing.T) {
+	w, err := NewRotateWriter(&FileLogConfig{FileDir: t.TempDir(), FileName: "app.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("write after close => %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second close => %v", err)
+	}
+}
+
+func TestFileLogConfig_Validate(t *testing.T) {
+	if err := (&FileLogConfig{Rotation: RotateBySize}).Validate(); err == nil {
+		t.Error("size rotation without maxSize accepted")
+	}
+	if err := (&FileLogConfig{Rotation: "weekly"}).Validate(); err == nil {
+		t.Error("unknown rotation accepted")
+	}
+	if err := (&FileLogConfig{Rotation: RotateByBoth, MaxSize: 10}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,340 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSamplingInitial    = 100
+	defaultSamplingThereafter = 100
+	defaultSamplingTick       = time.Second
+	defaultRateLimitBurst     = 10
+	defaultRateLimitInterval  = time.Second
+	// maxRateLimitSites idle call sites are forgotten beyond this many
+	maxRateLimitSites = 4096
+)
+
+// SamplingConfig
+/**
+ * @Description: serializes zap sampler config in toml/json/yaml. Each tick the
+ * first Initial entries with the same level and message are logged, then every
+ * Thereafter-th.
+ */
+type SamplingConfig struct {
+	Enable     bool          `toml:"enable" json:"enable" yaml:"enable"`
+	Initial    int           `toml:"initial" json:"initial" yaml:"initial"`          // Default 100.
+	Thereafter int           `toml:"thereafter" json:"thereafter" yaml:"thereafter"` // Default 100.
+	Tick       time.Duration `toml:"tick" json:"tick" yaml:"tick"`                   // Default 1s.
+}
+
+// RateLimitConfig
+/**
+ * @Description: serializes call site rate limiter config in toml/json/yaml.
+ * A call site logs at most Burst entries with the same level and message per
+ * Interval, the rest are counted and reported by one "N similar messages
+ * suppressed" entry once the interval is over.
+ */
+type RateLimitConfig struct {
+	Enable   bool          `toml:"enable" json:"enable" yaml:"enable"`
+	Burst    int           `toml:"burst" json:"burst" yaml:"burst"`          // Default 10.
+	Interval time.Duration `toml:"interval" json:"interval" yaml:"interval"` // Default 1s.
+}
+
+// Validate
+/**
+ * @Description: check for negative values
+ * @receiver c
+ * @return error
+ */
+func (c *SamplingConfig) Validate() error {
+	if c.Initial < 0 || c.Thereafter < 0 || c.Tick < 0 {
+		return fmt.Errorf("log.sampling: initial/thereafter/tick must not be negative")
+	}
+	return nil
+}
+
+// Validate
+/**
+ * @Description: check for negative values
+ * @receiver c
+ * @return error
+ */
+func (c *RateLimitConfig) Validate() error {
+	if c.Burst < 0 || c.Interval < 0 {
+		return fmt.Errorf("log.rateLimit: burst/interval must not be negative")
+	}
+	return nil
+}
+
+// NewSamplerCore
+/**
+ * @Description: zap sampler over core as configured by cfg, Enable is not checked
+ * @param core
+ * @param cfg
+ * @return zapcore.Core
+ */
+func NewSamplerCore(core zapcore.Core, cfg SamplingConfig) zapcore.Core {
+	initial, thereafter, tick := cfg.Initial, cfg.Thereafter, cfg.Tick
+	if initial == 0 {
+		initial = defaultSamplingInitial
+	}
+	if thereafter == 0 {
+		thereafter = defaultSamplingThereafter
+	}
+	if tick == 0 {
+		tick = defaultSamplingTick
+	}
+	return zapcore.NewSamplerWithOptions(core, tick, initial, thereafter)
+}
+
+// RateLimiter
+/**
+ * @Description: per call site limiter shared by every core of a ZapProperties.
+ * Summaries of suppressed entries are written once their interval is over,
+ * by the next entry of the call site or a timer, whichever comes first.
+ */
+type RateLimiter struct {
+	burst    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	sites   map[siteKey]*rateSite
+	timer   *time.Timer // flushes due summaries, armed while some are pending
+	stopped bool
+}
+
+// siteKey call site as zap resolved it and message, PCs differ between
+// inlined copies. The message tells apart sites sharing a caller through a
+// CallSkip too low, e.g. the package level functions with the default of 0.
+type siteKey struct {
+	file  string
+	line  int
+	level zapcore.Level
+	msg   string
+}
+
+type rateSite struct {
+	start      time.Time
+	count      int
+	suppressed int
+	last       zapcore.Entry
+	out        zapcore.Core // where the summary goes
+}
+
+// rateSummary entry reporting n suppressed entries, last being the latest of them
+type rateSummary struct {
+	last zapcore.Entry
+	n    int
+	out  zapcore.Core
+}
+
+// NewRateLimiter
+/**
+ * @Description: limiter as configured by cfg, Enable is not checked
+ * @param cfg
+ * @return *RateLimiter
+ */
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{burst: cfg.Burst, interval: cfg.Interval, now: time.Now, sites: make(map[siteKey]*rateSite)}
+	if l.burst == 0 {
+		l.burst = defaultRateLimitBurst
+	}
+	if l.interval == 0 {
+		l.interval = defaultRateLimitInterval
+	}
+	return l
+}
+
+// Stop
+/**
+ * @Description: stop flushing summaries from the timer, Sync still writes them
+ * @receiver l
+ */
+func (l *RateLimiter) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = true
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
+
+func rateSiteKey(ent zapcore.Entry) siteKey {
+	return siteKey{file: ent.Caller.File, line: ent.Caller.Line, level: ent.Level, msg: ent.Message}
+}
+
+// allow count ent against its call site, summary reports what the previous
+// interval suppressed, its n is zero when there is nothing to report
+func (l *RateLimiter) allow(ent zapcore.Entry, out zapcore.Core) (ok bool, summary rateSummary) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := rateSiteKey(ent)
+	s, found := l.sites[key]
+	if !found {
+		if len(l.sites) >= maxRateLimitSites {
+			l.prune(now)
+		}
+		s = &rateSite{start: now}
+		l.sites[key] = s
+	}
+	if now.Sub(s.start) >= l.interval {
+		summary = s.take()
+		s.start, s.count = now, 0
+	}
+	s.count++
+	if s.count > l.burst {
+		s.suppressed++
+		s.last, s.out = ent, out
+		l.arm(s.start.Add(l.interval).Sub(now))
+		return false, summary
+	}
+	return true, summary
+}
+
+// take the summary of s and start counting again
+func (s *rateSite) take() rateSummary {
+	summary := rateSummary{last: s.last, n: s.suppressed, out: s.out}
+	s.suppressed, s.last, s.out = 0, zapcore.Entry{}, nil
+	return summary
+}
+
+// arm the flush timer to fire in d unless it is already armed, caller holds mu
+func (l *RateLimiter) arm(d time.Duration) {
+	if l.timer != nil || l.stopped {
+		return
+	}
+	l.timer = time.AfterFunc(d, l.flushDue)
+}
+
+// flushDue write the summaries of call sites whose interval is over, then
+// re-arm for the next one
+func (l *RateLimiter) flushDue() {
+	now := l.now()
+	var due []rateSummary
+	l.mu.Lock()
+	l.timer = nil
+	next := time.Duration(-1)
+	for _, s := range l.sites {
+		if s.suppressed == 0 {
+			continue
+		}
+		if left := s.start.Add(l.interval).Sub(now); left > 0 {
+			if next < 0 || left < next {
+				next = left
+			}
+			continue
+		}
+		due = append(due, s.take())
+		s.start, s.count = now, 0
+	}
+	if next >= 0 {
+		l.arm(next)
+	}
+	l.mu.Unlock()
+	for _, summary := range due {
+		writeRateSummary(summary)
+	}
+}
+
+// pending take the unreported summaries of every call site
+func (l *RateLimiter) pending() []rateSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []rateSummary
+	for _, s := range l.sites {
+		if s.suppressed > 0 {
+			out = append(out, s.take())
+		}
+	}
+	return out
+}
+
+// prune forget call sites idle for an interval, caller holds mu
+func (l *RateLimiter) prune(now time.Time) {
+	for key, s := range l.sites {
+		if s.suppressed == 0 && now.Sub(s.start) >= l.interval {
+			delete(l.sites, key)
+		}
+	}
+}
+
+// rateLimitCore
+/**
+ * @Description: drops entries over the call site budget of its RateLimiter.
+ * zap resolves the caller after Check, so entries are counted on Write.
+ */
+type rateLimitCore struct {
+	zapcore.Core
+	l *RateLimiter
+}
+
+// NewRateLimitCore
+/**
+ * @Description: wrap core, cores sharing l share the budget of each call site
+ * @param core
+ * @param l
+ * @return zapcore.Core
+ */
+func NewRateLimitCore(core zapcore.Core, l *RateLimiter) zapcore.Core {
+	return &rateLimitCore{Core: core, l: l}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), l: c.l}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// never swallow entries that panic or exit
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *rateLimitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ok, summary := c.l.allow(ent, c.Core)
+	writeRateSummary(summary)
+	if !ok {
+		return nil
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (c *rateLimitCore) Sync() error {
+	for _, summary := range c.l.pending() {
+		writeRateSummary(summary)
+	}
+	return c.Core.Sync()
+}
+
+// writeRateSummary write the "N similar messages suppressed" entry of summary, if any
+func writeRateSummary(summary rateSummary) {
+	if summary.n == 0 || summary.out == nil {
+		return
+	}
+	last := summary.last
+	ent := zapcore.Entry{
+		Level:      last.Level,
+		Time:       time.Now(),
+		LoggerName: last.LoggerName,
+		Message:    fmt.Sprintf("%d similar messages suppressed", summary.n),
+		Caller:     last.Caller,
+	}
+	if ce := summary.out.Check(ent, nil); ce != nil {
+		ce.Write(zap.Int("suppressed", summary.n), zap.String("lastMessage", last.Message))
+	}
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestInitLogger_Sampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", Sampling: SamplingConfig{Enable: true, Initial: 2, Thereafter: 5, Tick: time.Hour}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+	for i := 0; i < 12; i++ {
+		Error("hot loop")
+	}
+	// 1st, 2nd, then every 5th of the rest: 7th and 12th
+	if n := strings.Count(buf.String(), "hot loop"); n != 4 {
+		t.Errorf("sampled => %d!=4", n)
+	}
+}
+
+func TestInitLogger_RateLimit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", CallSkip: 3, RateLimit: RateLimitConfig{Enable: true, Burst: 3, Interval: time.Hour}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+	clock := time.Now()
+	p.limiter.now = func() time.Time { return clock }
+
+	siteA := func(i int) { Error("site a", zap.Int("i", i)) } // one call site, varying content
+	for i := 0; i < 10; i++ {
+		siteA(i)
+	}
+	Error("site b")
+	if n := strings.Count(buf.String(), "site a"); n != 3 {
+		t.Errorf("site a logged %d!=3", n)
+	}
+	if !strings.Contains(buf.String(), "site b") {
+		t.Error("other call site limited")
+	}
+
+	clock = clock.Add(time.Hour)
+	siteA(10)
+	out := buf.String()
+	if !strings.Contains(out, "7 similar messages suppressed") || !strings.Contains(out, "sampling_test.go") {
+		t.Errorf("summary missing: %s", out)
+	}
+
+	buf.Reset()
+	for i := 0; i < 5; i++ {
+		Warn("site c")
+	}
+	_ = Sync()
+	if !strings.Contains(buf.String(), "2 similar messages suppressed") {
+		t.Errorf("Sync did not flush summary: %s", buf.String())
+	}
+}
+
+func TestInitLogger_RateLimitTimer(t *testing.T) {
+	buf := &gateWriter{gate: make(chan struct{})}
+	close(buf.gate)
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", RateLimit: RateLimitConfig{Enable: true, Burst: 1, Interval: 50 * time.Millisecond}}
+	l, p, err := InitLoggerWithWriteSyncer(cfg, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+	for i := 0; i < 4; i++ {
+		Warn("quiet")
+	}
+	// no further entries of the call site, the timer reports them
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(buf.String(), "3 similar messages suppressed") {
+		if time.Now().After(deadline) {
+			t.Fatalf("summary not flushed: %s", buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConfig_ValidateSampling(t *testing.T) {
+	if err := (&Config{Sampling: SamplingConfig{Initial: -1}}).Validate(); err == nil {
+		t.Error("negative sampling accepted")
+	}
+	if err := (&Config{RateLimit: RateLimitConfig{Interval: -time.Second}}).Validate(); err == nil {
+		t.Error("negative rate limit accepted")
+	}
+}
@@ -0,0 +1,206 @@
+package log
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink types of SinkConfig.Type
+const (
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkHTTP   = "http"
+)
+
+// SinkConfig
+/**
+ * @Description: serializes one extra log output in toml/json/yaml.
+ * An empty Level follows Config.Level and SetLevel, an empty Format uses Config.Format.
+ */
+type SinkConfig struct {
+	Type   string         `toml:"type" json:"type" yaml:"type"`       // file, syslog or http.
+	Level  string         `toml:"level" json:"level" yaml:"level"`    // Sink level, fixed once set.
+	Format string         `toml:"format" json:"format" yaml:"format"` // Sink format.
+	File   FileLogConfig  `toml:"file" json:"file" yaml:"file"`       // Rotating file, type file.
+	Syslog SyslogConfig   `toml:"syslog" json:"syslog" yaml:"syslog"` // Local syslog, type syslog.
+	HTTP   HTTPSinkConfig `toml:"http" json:"http" yaml:"http"`       // Batch push, type http.
+}
+
+// Validate
+/**
+ * @Description: check type, level, format and the settings of the type
+ * @receiver c
+ * @return error
+ */
+func (c *SinkConfig) Validate() error {
+	if c.Level != "" {
+		if err := new(Level).Unpack(c.Level); err != nil {
+			return fmt.Errorf("log.sinks.level: %w", err)
+		}
+	}
+	if c.Format != "" {
+		if _, err := BuildEncoder(c.Format); err != nil {
+			return fmt.Errorf("log.sinks.format: %w", err)
+		}
+	}
+	switch c.Type {
+	case SinkFile:
+		if c.File.FileName == "" {
+			return fmt.Errorf("log.sinks.file: fileName is required")
+		}
+		return c.File.Validate()
+	case SinkSyslog:
+		return c.Syslog.Validate()
+	case SinkHTTP:
+		return c.HTTP.Validate()
+	default:
+		return fmt.Errorf("log.sinks: unknown type '%s'", c.Type)
+	}
+}
+
+// openSink
+/**
+ * @Description: open the writer of c
+ * @param c
+ * @return zapcore.WriteSyncer
+ * @return error
+ */
+func openSink(c *SinkConfig) (zapcore.WriteSyncer, error) {
+	switch c.Type {
+	case SinkFile:
+		return NewRotateWriter(&c.File)
+	case SinkSyslog:
+		return NewSyslogWriter(c.Syslog)
+	case SinkHTTP:
+		return NewHTTPWriter(c.HTTP)
+	default:
+		return nil, fmt.Errorf("log.sinks: unknown type '%s'", c.Type)
+	}
+}
+
+// writerKey the writer of a sink, the same key means the same writer
+func (c *SinkConfig) writerKey() string {
+	switch c.Type {
+	case SinkFile:
+		return writerKey(SinkFile, c.File)
+	case SinkSyslog:
+		return writerKey(SinkSyslog, c.Syslog)
+	default:
+		return writerKey(c.Type, c.HTTP)
+	}
+}
+
+// writerKey type and config a writer was opened with
+func writerKey(typ string, cfg interface{}) string {
+	return fmt.Sprintf("%s %+v", typ, cfg)
+}
+
+// takeWriter writer prev opened for key, unless p already took it
+func (p *ZapProperties) takeWriter(prev *ZapProperties, key string) (zapcore.WriteSyncer, bool) {
+	if prev == nil || p.writers[key] != nil {
+		return nil, false
+	}
+	w, ok := prev.writers[key]
+	return w, ok
+}
+
+func (p *ZapProperties) addWriter(key string, w zapcore.WriteSyncer) {
+	if p.writers == nil {
+		p.writers = make(map[string]zapcore.WriteSyncer)
+	}
+	p.writers[key] = w
+	if c, ok := w.(io.Closer); ok {
+		p.closers = append(p.closers, c)
+	}
+}
+
+// addSinks open every sink of cfg, lv is the level of sinks without their own.
+// Writers prev opened with the same config are taken over instead.
+func (p *ZapProperties) addSinks(cfg *Config, lv zap.AtomicLevel, prev *ZapProperties) error {
+	for i := range cfg.Sinks {
+		sc := &cfg.Sinks[i]
+		if err := sc.Validate(); err != nil {
+			return err
+		}
+		format := sc.Format
+		if format == "" {
+			format = cfg.Format
+		}
+		enc, err := BuildEncoder(format)
+		if err != nil {
+			return err
+		}
+		level := lv
+		if sc.Level != "" {
+			l := new(Level)
+			_ = l.Unpack(sc.Level)
+			level = zap.NewAtomicLevelAt(l.zapLevel())
+		}
+		key := sc.writerKey()
+		out, ok := p.takeWriter(prev, key)
+		if !ok {
+			if out, err = openSink(sc); err != nil {
+				return fmt.Errorf("log.sinks.%s: %w", sc.Type, err)
+			}
+		}
+		p.addWriter(key, out)
+		p.sinks = append(p.sinks, sink{encoder: enc, out: out, level: level})
+	}
+	return nil
+}
+
+// Close
+/**
+ * @Description: flush and close the writers opened for this logger, it must
+ * not be used afterwards
+ * @receiver p
+ * @return error first error
+ */
+func (p *ZapProperties) Close() error {
+	return p.closeExcept(nil)
+}
+
+// closeExcept Close, leaving open the writers next took over
+func (p *ZapProperties) closeExcept(next *ZapProperties) error {
+	if p.limiter != nil {
+		p.limiter.Stop()
+	}
+	var first error
+	for _, c := range p.closers {
+		if next.writes(c) {
+			continue
+		}
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	p.closers = nil
+	return first
+}
+
+// writes reports whether c is one of the writers of p, false for a nil p
+func (p *ZapProperties) writes(c io.Closer) bool {
+	if p == nil {
+		return false
+	}
+	for _, w := range p.writers {
+		if wc, ok := w.(io.Closer); ok && wc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// dropped sum of entries lost by writers of this logger
+func (p *ZapProperties) dropped() uint64 {
+	var n uint64
+	for _, c := range p.closers {
+		if d, ok := c.(interface{ Dropped() uint64 }); ok {
+			n += d.Dropped()
+		}
+	}
+	return n
+}
@@ -0,0 +1,262 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// pushServer records request bodies, failing the first fail requests with status
+type pushServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+	calls  int
+}
+
+func newPushServer(t *testing.T, fail, status int) *pushServer {
+	s := &pushServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls++
+		if s.calls <= fail {
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("X-Scope-OrgID") != "tenant" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.bodies = append(s.bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *pushServer) snapshot() ([]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...), s.calls
+}
+
+func TestHTTPWriter_LokiRetry(t *testing.T) {
+	srv := newPushServer(t, 2, http.StatusServiceUnavailable)
+	w, err := NewHTTPWriter(HTTPSinkConfig{URL: srv.URL, Labels: map[string]string{"app": "api"}, Headers: map[string]string{"X-Scope-OrgID": "tenant"}, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("first\n"))
+	_, _ = w.WriteLevel(zapcore.ErrorLevel, []byte("second\n"))
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("third\n"))
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	bodies, calls := srv.snapshot()
+	if calls != 3 || len(bodies) != 1 {
+		t.Fatalf("calls %d bodies %d", calls, len(bodies))
+	}
+	var push struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &push); err != nil {
+		t.Fatal(err)
+	}
+	if len(push.Streams) != 2 || push.Streams[0].Stream["level"] != "info" || push.Streams[0].Stream["app"] != "api" {
+		t.Fatalf("streams => %+v", push.Streams)
+	}
+	if v := push.Streams[0].Values; len(v) != 2 || v[0][1] != "first" || v[1][1] != "third" {
+		t.Errorf("info values => %v", v)
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("dropped => %d", w.Dropped())
+	}
+}
+
+func TestHTTPWriter_NoRetryOnClientError(t *testing.T) {
+	srv := newPushServer(t, 1, http.StatusBadRequest)
+	w, err := NewHTTPWriter(HTTPSinkConfig{URL: srv.URL, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.Write([]byte("rejected\n"))
+	if err := w.Sync(); err == nil {
+		t.Error("400 not reported")
+	}
+	if _, calls := srv.snapshot(); calls != 1 || w.Dropped() != 1 {
+		t.Errorf("calls %d dropped %d", calls, w.Dropped())
+	}
+}
+
+func TestHTTPWriter_CloseTimeout(t *testing.T) {
+	// black hole, requests hang until the client gives up
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	w, err := NewHTTPWriter(HTTPSinkConfig{URL: srv.URL, BatchSize: 1, FlushInterval: time.Hour, Timeout: time.Hour, CloseTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("x\n"))
+	}
+	start := time.Now()
+	if err := w.Close(); err == nil {
+		t.Error("undelivered entries not reported")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("close took %s", d)
+	}
+	if w.Dropped() != 20 {
+		t.Errorf("dropped => %d", w.Dropped())
+	}
+}
+
+func TestHTTPWriter_SyncTimeout(t *testing.T) {
+	// black hole, the background send holds the writer until the client gives up
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	w, err := NewHTTPWriter(HTTPSinkConfig{URL: srv.URL, BatchSize: 1, FlushInterval: time.Hour, Timeout: 3 * time.Second, CloseTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("x\n"))
+	<-started
+	_, _ = w.WriteLevel(zapcore.ErrorLevel, []byte("y\n"))
+	start := time.Now()
+	if err := w.Sync(); err != context.DeadlineExceeded {
+		t.Errorf("sync => %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("sync took %s", d)
+	}
+}
+
+func TestHTTPWriter_ElasticsearchBulk(t *testing.T) {
+	srv := newPushServer(t, 0, 0)
+	w, err := NewHTTPWriter(HTTPSinkConfig{URL: srv.URL, Protocol: ProtocolElasticsearch, Index: "app", Headers: map[string]string{"X-Scope-OrgID": "tenant"}, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(`{"message":"json"}` + "\n"))
+	_, _ = w.Write([]byte("plain text\n"))
+	_ = w.Close()
+	bodies, _ := srv.snapshot()
+	if len(bodies) != 1 {
+		t.Fatalf("bodies => %v", bodies)
+	}
+	lines := strings.Split(strings.TrimSuffix(bodies[0], "\n"), "\n")
+	if len(lines) != 4 || lines[0] != `{"index":{"_index":"app"}}` || lines[1] != `{"message":"json"}` {
+		t.Fatalf("bulk => %q", bodies[0])
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(lines[3]), &doc); err != nil || doc["message"] != "plain text" {
+		t.Errorf("wrapped doc => %s", lines[3])
+	}
+}
+
+func TestInitLogger_Sinks(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Skip("unixgram not supported:", err)
+	}
+	defer conn.Close()
+	srv := newPushServer(t, 0, 0)
+
+	cfg := &Config{Level: "info", StdLevel: "critical", Format: "json", Sinks: []SinkConfig{
+		{Type: SinkSyslog, Level: "warning", Format: FormatLogfmt, Syslog: SyslogConfig{Address: sock, Facility: "local0", AppName: "api"}},
+		{Type: SinkHTTP, HTTP: HTTPSinkConfig{URL: srv.URL, Headers: map[string]string{"X-Scope-OrgID": "tenant"}, FlushInterval: time.Hour}},
+		{Type: SinkFile, File: FileLogConfig{FileDir: dir, FileName: "app.log"}},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	l, p, err := InitLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+	Info("routine")
+	Warn("disk almost full")
+	_ = Sync()
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0 (16) * 8 + warning (4)
+	if !strings.HasPrefix(msg, "<132>1 ") || !strings.Contains(msg, " api ") || !strings.Contains(msg, `msg="disk almost full"`) {
+		t.Errorf("syslog => %q", msg)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := conn.Read(buf); err == nil {
+		t.Errorf("info reached the warn sink: %q", buf[:n])
+	}
+
+	bodies, _ := srv.snapshot()
+	if len(bodies) != 1 || !strings.Contains(bodies[0], "routine") || !strings.Contains(bodies[0], "disk almost full") {
+		t.Errorf("http => %v", bodies)
+	}
+	if err := p.Close(); err != nil {
+		t.Error(err)
+	}
+	file, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if strings.Count(string(file), "\n") != 2 {
+		t.Errorf("file => %q", file)
+	}
+}
+
+func TestSinkConfig_Validate(t *testing.T) {
+	for _, sc := range []SinkConfig{
+		{Type: "kafka"},
+		{Type: SinkFile},
+		{Type: SinkHTTP},
+		{Type: SinkHTTP, HTTP: HTTPSinkConfig{URL: "http://x", Protocol: "splunk"}},
+		{Type: SinkSyslog, Syslog: SyslogConfig{Facility: "local9"}},
+		{Type: SinkSyslog, Level: "loud"},
+		{Type: SinkSyslog, Format: "xml"},
+	} {
+		sc := sc
+		if err := sc.Validate(); err == nil {
+			t.Errorf("%+v accepted", sc)
+		}
+	}
+}
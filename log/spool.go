@@ -0,0 +1,208 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSpoolMaxSize = 100 // MB
+	spoolSuffix         = ".batch"
+)
+
+// SpoolConfig
+/**
+ * @Description: serializes HTTP sink spool config in toml/json/yaml. Batches
+ * the endpoint did not take are kept in Dir and replayed in order, also after
+ * a restart; beyond MaxSize the oldest batches are dropped.
+ */
+type SpoolConfig struct {
+	Dir     string `toml:"dir" json:"dir" yaml:"dir"`             // Spool directory, empty disables spooling.
+	MaxSize int    `toml:"maxSize" json:"maxSize" yaml:"maxSize"` // Cap on spooled batches, in MB, default 100.
+}
+
+// spoolFile one batch, named <seq>-<entries>.batch
+type spoolFile struct {
+	path    string
+	seq     uint64
+	entries int
+	size    int64
+}
+
+// spoolRecord one entry of a spooled batch, a JSON line
+type spoolRecord struct {
+	Level int8   `json:"l"`
+	Time  int64  `json:"t"`
+	Line  string `json:"m"`
+}
+
+// spool
+/**
+ * @Description: directory of batches waiting for the endpoint, oldest first.
+ * Not safe for concurrent use, HTTPWriter calls it holding sendSem.
+ */
+type spool struct {
+	dir   string
+	max   int64
+	files []spoolFile
+	size  int64
+	seq   uint64
+}
+
+// openSpool create dir and pick up batches left by an earlier run
+func openSpool(cfg SpoolConfig) (*spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &spool{dir: cfg.Dir, max: int64(cfg.MaxSize) * megabyte}
+	if s.max <= 0 {
+		s.max = defaultSpoolMaxSize * megabyte
+	}
+	if err := s.scan(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan list the batches in dir, including those other writers sharing it
+// spooled or already removed since the last scan
+func (s *spool) scan() error {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	files := make([]spoolFile, 0, len(infos))
+	var size int64
+	for _, info := range infos {
+		f, ok := parseSpoolName(info.Name())
+		if !ok || info.IsDir() {
+			continue
+		}
+		f.path = filepath.Join(s.dir, info.Name())
+		f.size = info.Size()
+		files = append(files, f)
+		size += f.size
+		if f.seq > s.seq {
+			s.seq = f.seq
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+	s.files, s.size = files, size
+	return nil
+}
+
+func parseSpoolName(name string) (spoolFile, bool) {
+	if !strings.HasSuffix(name, spoolSuffix) {
+		return spoolFile{}, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, spoolSuffix), "-", 2)
+	if len(parts) != 2 {
+		return spoolFile{}, false
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return spoolFile{}, false
+	}
+	entries, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return spoolFile{}, false
+	}
+	return spoolFile{seq: seq, entries: entries}, true
+}
+
+func (s *spool) empty() bool {
+	return len(s.files) == 0
+}
+
+// push
+/**
+ * @Description: append a batch, dropping the oldest batches over the size cap
+ * @param batch
+ * @return int entries dropped to make room
+ * @return error
+ */
+func (s *spool) push(batch []httpEntry) (int, error) {
+	f := spoolFile{entries: len(batch)}
+	tmp, err := ioutil.TempFile(s.dir, ".spool-")
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(bw)
+	for _, e := range batch {
+		if err = enc.Encode(spoolRecord{Level: int8(e.lvl), Time: e.time.UnixNano(), Line: string(e.line)}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	for err == nil {
+		// a link makes the batch visible whole or not at all, and unlike a
+		// rename fails instead of replacing a batch another writer took the seq for
+		s.seq++
+		f.seq = s.seq
+		f.path = filepath.Join(s.dir, fmt.Sprintf("%020d-%d%s", f.seq, f.entries, spoolSuffix))
+		if err = os.Link(tmp.Name(), f.path); err == nil || !os.IsExist(err) {
+			break
+		}
+		err = nil
+	}
+	_ = os.Remove(tmp.Name())
+	if err != nil {
+		return 0, err
+	}
+	if info, err := os.Stat(f.path); err == nil {
+		f.size = info.Size()
+	}
+	s.files = append(s.files, f)
+	s.size += f.size
+
+	dropped := 0
+	for s.size > s.max && len(s.files) > 1 {
+		dropped += s.remove()
+	}
+	return dropped, nil
+}
+
+// peek read the oldest batch
+func (s *spool) peek() ([]httpEntry, error) {
+	b, err := ioutil.ReadFile(s.files[0].path)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]httpEntry, 0, s.files[0].entries)
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		var rec spoolRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("log: corrupt spool batch %s: %w", s.files[0].path, err)
+		}
+		batch = append(batch, httpEntry{lvl: zapcore.Level(rec.Level), time: time.Unix(0, rec.Time), line: []byte(rec.Line)})
+	}
+	return batch, nil
+}
+
+// remove delete the oldest batch, returns its number of entries
+func (s *spool) remove() int {
+	f := s.files[0]
+	_ = os.Remove(f.path)
+	s.size -= f.size
+	s.files = s.files[1:]
+	return f.entries
+}
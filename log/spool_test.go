@@ -0,0 +1,192 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// outageServer answers 503 until up, then records the pushed lines in order
+type outageServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	up    bool
+	lines []string
+}
+
+func newOutageServer(t *testing.T) *outageServer {
+	s := &outageServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var push struct {
+			Streams []lokiStream `json:"streams"`
+		}
+		_ = json.Unmarshal(body, &push)
+		for _, st := range push.Streams {
+			for _, v := range st.Values {
+				s.lines = append(s.lines, v[1])
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *outageServer) recover() {
+	s.mu.Lock()
+	s.up = true
+	s.mu.Unlock()
+}
+
+func (s *outageServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+// entries number of spooled entries
+func (s *spool) entries() int {
+	n := 0
+	for _, f := range s.files {
+		n += f.entries
+	}
+	return n
+}
+
+func spooled(w *HTTPWriter) int {
+	w.sendSem <- struct{}{}
+	defer func() { <-w.sendSem }()
+	return w.spool.entries()
+}
+
+func TestHTTPWriter_SpoolReplay(t *testing.T) {
+	srv := newOutageServer(t)
+	cfg := HTTPSinkConfig{URL: srv.URL, BatchSize: 2, FlushInterval: time.Hour, MaxRetries: 1, RetryBackoff: time.Millisecond, Spool: SpoolConfig{Dir: t.TempDir()}}
+	w, err := NewHTTPWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, s := range []string{"a", "b", "c"} {
+		_, _ = w.WriteLevel(zapcore.InfoLevel, []byte(s+"\n"))
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	// endpoint still down, new entries queue behind the spooled ones
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("d\n"))
+	_ = w.Sync()
+	if n := spooled(w); n != 4 || w.Dropped() != 0 {
+		t.Fatalf("spooled %d dropped %d", n, w.Dropped())
+	}
+
+	srv.recover()
+	_, _ = w.WriteLevel(zapcore.InfoLevel, []byte("e\n"))
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.received(); len(got) != 5 || got[0] != "a" || got[3] != "d" || got[4] != "e" {
+		t.Errorf("received => %v", got)
+	}
+	if n := spooled(w); n != 0 {
+		t.Errorf("left in spool => %d", n)
+	}
+}
+
+func TestHTTPWriter_SpoolSurvivesRestart(t *testing.T) {
+	srv := newOutageServer(t)
+	cfg := HTTPSinkConfig{URL: srv.URL, FlushInterval: time.Hour, MaxRetries: 1, RetryBackoff: time.Millisecond, Spool: SpoolConfig{Dir: t.TempDir()}}
+	w, err := NewHTTPWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("before restart\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.recover()
+	w, err = NewHTTPWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.Write([]byte("after restart\n"))
+	_ = w.Sync()
+	if got := srv.received(); len(got) != 2 || got[0] != "before restart" {
+		t.Errorf("received => %v", got)
+	}
+}
+
+func TestHTTPWriter_SpoolSizeCap(t *testing.T) {
+	srv := newOutageServer(t)
+	cfg := HTTPSinkConfig{URL: srv.URL, BatchSize: 1, FlushInterval: time.Hour, MaxRetries: 1, RetryBackoff: time.Millisecond, Spool: SpoolConfig{Dir: t.TempDir()}}
+	w, err := NewHTTPWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.spool.max = 100 // bytes, room for about two batches
+	for _, s := range []string{"one", "two", "three", "four"} {
+		_, _ = w.Write([]byte(s + "\n"))
+	}
+	_ = w.Sync()
+	if w.Dropped() == 0 || int(w.Dropped())+spooled(w) != 4 {
+		t.Fatalf("dropped %d spooled %d", w.Dropped(), spooled(w))
+	}
+
+	srv.recover()
+	_ = w.Sync()
+	got := srv.received()
+	if len(got) == 0 || got[len(got)-1] != "four" {
+		t.Errorf("newest entries not kept => %v", got)
+	}
+}
+
+func TestHTTPSinkConfig_SpoolValidate(t *testing.T) {
+	cfg := HTTPSinkConfig{URL: "http://localhost", Spool: SpoolConfig{Dir: "spool", MaxSize: -1}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("negative spool maxSize accepted")
+	}
+}
+
+func TestSpool_SharedDir(t *testing.T) {
+	dir := t.TempDir()
+	a, err := openSpool(SpoolConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := openSpool(SpoolConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := []httpEntry{{lvl: zapcore.InfoLevel, time: time.Now(), line: []byte("x")}}
+	for _, s := range []*spool{a, b, a} {
+		if _, err := s.push(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.files) != 3 || b.entries() != 3 {
+		t.Fatalf("batches overwritten => %v", b.files)
+	}
+	b.remove()
+	if err := a.scan(); err != nil || len(a.files) != 2 {
+		t.Errorf("removed batch still listed => %v %v", a.files, err)
+	}
+}
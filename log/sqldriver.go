@@ -0,0 +1,316 @@
+package log
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// _gSQLDriverLogger output of SQLDriver, named sql so its level can be set through Config.Modules
+var _gSQLDriverLogger = Named("sql").WithOptions(zap.AddCallerSkip(2))
+
+// SQLDriver
+/**
+ * @Description: database/sql driver logging every statement with the fields of
+ * OrmLoggerAdapter.AfterSQL, e.g.
+ *  sql.Register("mysql-log", log.WrapSQLDriver(&mysql.MySQLDriver{}, cfg))
+ *  db, err := sql.Open("mysql-log", dsn)
+ * or sql.OpenDB(d.Connector(connector)) for drivers handing out connectors.
+ */
+type SQLDriver struct {
+	driver driver.Driver
+	sql    *sqlLogger
+}
+
+// WrapSQLDriver
+/**
+ * @Description: wrap d, statements are logged as configured by cfg
+ * @param d
+ * @param cfg
+ * @return *SQLDriver
+ */
+func WrapSQLDriver(d driver.Driver, cfg *SQLLogConfig) *SQLDriver {
+	return &SQLDriver{driver: d, sql: newSQLLogger(*cfg)}
+}
+
+// Open implements driver.Driver
+func (d *SQLDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, d: d}, nil
+}
+
+// OpenConnector implements driver.DriverContext
+func (d *SQLDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return d.Connector(c), nil
+	}
+	return &sqlConnector{d: d, dsn: name}, nil
+}
+
+// Connector
+/**
+ * @Description: wrap a connector of the wrapped driver, for sql.OpenDB
+ * @receiver d
+ * @param c
+ * @return driver.Connector
+ */
+func (d *SQLDriver) Connector(c driver.Connector) driver.Connector {
+	return &sqlConnector{d: d, base: c}
+}
+
+// Stats
+/**
+ * @Description: count, errors and latency percentiles per statement fingerprint, most executed first
+ * @receiver d
+ * @return []SQLStat
+ */
+func (d *SQLDriver) Stats() []SQLStat {
+	return d.sql.snapshot()
+}
+
+// ResetStats forget the stats collected so far
+func (d *SQLDriver) ResetStats() {
+	d.sql.reset()
+}
+
+// StatsHandler
+/**
+ * @Description: serves Stats as JSON, e.g. mounted on an admin port
+ * @receiver d
+ * @return http.Handler
+ */
+func (d *SQLDriver) StatsHandler() http.Handler {
+	return sqlStatsHandler(d.Stats)
+}
+
+// log a finished statement, driver.ErrSkip only hands it back to database/sql
+func (d *SQLDriver) log(ctx context.Context, query string, args []driver.NamedValue, start time.Time, res driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	e := d.sql.observe(query, values, time.Since(start), err)
+	if e.skip {
+		return
+	}
+	var rows int64
+	var rowErr error
+	if res != nil {
+		rows, rowErr = res.RowsAffected()
+	}
+	msg := fmt.Sprintf("[SQL]Part: %s Args: %v Rows %d Err %v", "", e.args, rows, rowErr)
+	if ce := _gSQLDriverLogger.Check(e.level, msg); ce != nil {
+		ce.Write(append(Ctx2Fields(ctx), e.fields...)...)
+	}
+}
+
+// sqlConnector wraps the connections of base, or of the driver opened with dsn
+type sqlConnector struct {
+	d    *SQLDriver
+	base driver.Connector
+	dsn  string
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var (
+		conn driver.Conn
+		err  error
+	)
+	if c.base != nil {
+		conn, err = c.base.Connect(ctx)
+	} else {
+		conn, err = c.d.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, d: c.d}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// sqlConn logs statements run on the wrapped connection, optional interfaces
+// it lacks behave as database/sql does without them
+type sqlConn struct {
+	driver.Conn
+	d *SQLDriver
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, query: query, c: c}, nil
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return c.Prepare(query)
+	}
+	stmt, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, query: query, c: c}, nil
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Begin()
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	c.d.log(ctx, query, args, start, res, err)
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.d.log(ctx, query, args, start, nil, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlStmt logs executions of a prepared statement, argument checks and
+// conversions stay with the wrapped statement
+type sqlStmt struct {
+	driver.Stmt
+	query string
+	c     *sqlConn
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return s.c.CheckNamedValue(nv)
+}
+
+func (s *sqlStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if se, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = se.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.c.d.log(ctx, s.query, args, start, res, err)
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if sq, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = sq.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(ctx, args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.c.d.log(ctx, s.query, args, start, nil, err)
+	return rows, err
+}
+
+// namedValues args for drivers without context support, which take no names
+func namedValues(ctx context.Context, args []driver.NamedValue) ([]driver.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
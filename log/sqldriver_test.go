@@ -0,0 +1,131 @@
+package log
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeDriver prepares every statement, those containing fail return an error
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no tx") }
+
+type fakeStmt string
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(string(s), "fail") {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(2), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"a"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func TestSQLDriver(t *testing.T) {
+	buf := xormOutput(t)
+	d := WrapSQLDriver(fakeDriver{}, &SQLLogConfig{MaskColumns: []string{"password"}})
+	connector, err := d.OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	ctx := context.WithValue(context.Background(), RequestIdKey, "r1")
+
+	if _, err := db.ExecContext(ctx, "UPDATE user SET password = ? WHERE id = ?", "s3cret", 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "fail"); err == nil {
+		t.Fatal("expected an error")
+	}
+	rows, err := db.QueryContext(ctx, "SELECT a FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	lines := xormLines(t, buf)
+	if len(lines) != 3 {
+		t.Fatalf("lines => %v", lines)
+	}
+	for _, line := range lines {
+		if line["logger"] != "sql" || line[Category.ToString()] != "SQL" || line[SessionId.ToString()] != "r1" || line[QueryText.ToString()] == nil {
+			t.Errorf("fields => %v", line)
+		}
+	}
+	if msg := lines[0]["msg"].(string); strings.Contains(msg, "s3cret") || !strings.Contains(msg, "[*** 7] Rows 2") {
+		t.Errorf("exec => %s", msg)
+	}
+	if lines[1]["level"] != "ERROR" || lines[1][Errors.ToString()] != "boom" || lines[2][QueryText.ToString()] != "SELECT a FROM t" {
+		t.Errorf("fail/query => %v", lines)
+	}
+	if stats := d.Stats(); len(stats) != 3 {
+		t.Errorf("stats => %v", stats)
+	}
+}
+
+// upperStmt converts every argument to upper case and keeps the last ones
+type upperStmt struct {
+	fakeStmt
+	got *[]driver.Value
+}
+
+func (s upperStmt) ColumnConverter(int) driver.ValueConverter { return upperConverter{} }
+func (s upperStmt) Exec(args []driver.Value) (driver.Result, error) {
+	*s.got = args
+	return driver.RowsAffected(1), nil
+}
+
+type upperConverter struct{}
+
+func (upperConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	return strings.ToUpper(v.(string)), nil
+}
+
+type upperConn struct {
+	fakeConn
+	got *[]driver.Value
+}
+
+func (c upperConn) Prepare(query string) (driver.Stmt, error) {
+	return upperStmt{fakeStmt: fakeStmt(query), got: c.got}, nil
+}
+
+type upperDriver struct{ got *[]driver.Value }
+
+func (d upperDriver) Open(string) (driver.Conn, error) { return upperConn{got: d.got}, nil }
+
+func TestSQLDriver_ColumnConverter(t *testing.T) {
+	xormOutput(t)
+	var got []driver.Value
+	connector, err := WrapSQLDriver(upperDriver{got: &got}, &SQLLogConfig{}).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	if _, err := db.Exec("UPDATE t SET a = ?", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "X" {
+		t.Errorf("converted args => %v", got)
+	}
+}
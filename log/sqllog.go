@@ -0,0 +1,224 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	maxSQLLength = 2048
+	maxSQLArgs   = 100
+	// sqlStatWindow latencies kept per fingerprint for percentiles
+	sqlStatWindow = 512
+	// maxSQLFingerprints statements beyond are counted under sqlOtherFingerprint
+	maxSQLFingerprints  = 1000
+	sqlOtherFingerprint = "other"
+)
+
+// SQLLogConfig
+/**
+ * @Description: serializes SQL logging config in toml/json/yaml, shared by the ORM adapters.
+ * Failed statements are logged at Error, those slower than SlowThreshold at
+ * Warn with slow=true, the rest at Info and sampled by SampleFast.
+ */
+type SQLLogConfig struct {
+	SlowThreshold time.Duration `toml:"slowThreshold" json:"slowThreshold" yaml:"slowThreshold"` // 0 disables slow query detection.
+	MaskColumns   []string      `toml:"maskColumns" json:"maskColumns" yaml:"maskColumns"`       // Args bound to these columns are masked, e.g. password.
+	MaskArgs      []int         `toml:"maskArgs" json:"maskArgs" yaml:"maskArgs"`                // Arg positions masked, from 0.
+	SampleFast    int           `toml:"sampleFast" json:"sampleFast" yaml:"sampleFast"`          // Log 1 in N fast statements per fingerprint, default 1 logs all.
+}
+
+// Validate
+/**
+ * @Description: check for negative values
+ * @receiver c
+ * @return error
+ */
+func (c *SQLLogConfig) Validate() error {
+	if c.SlowThreshold < 0 || c.SampleFast < 0 {
+		return fmt.Errorf("log.sql: slowThreshold/sampleFast must not be negative")
+	}
+	for _, p := range c.MaskArgs {
+		if p < 0 {
+			return fmt.Errorf("log.sql: maskArgs must not be negative")
+		}
+	}
+	return nil
+}
+
+// SQLStat
+/**
+ * @Description: executions of one statement fingerprint, latencies over the
+ * last executions
+ */
+type SQLStat struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       uint64        `json:"count"`
+	Errors      uint64        `json:"errors"`
+	Slow        uint64        `json:"slow"`
+	P50         time.Duration `json:"p50"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+}
+
+type sqlStat struct {
+	count, errors, slow uint64
+	max                 time.Duration
+	window              [sqlStatWindow]time.Duration
+}
+
+// sqlLogger
+/**
+ * @Description: decides how a statement is logged and keeps per-fingerprint
+ * stats, the ORM adapters add their own message and context fields
+ */
+type sqlLogger struct {
+	cfg    SQLLogConfig
+	masker *sqlMasker
+
+	mu    sync.Mutex
+	stats map[string]*sqlStat
+}
+
+func newSQLLogger(cfg SQLLogConfig) *sqlLogger {
+	if cfg.SampleFast <= 0 {
+		cfg.SampleFast = 1
+	}
+	return &sqlLogger{cfg: cfg, masker: newSQLMasker(cfg.MaskColumns, cfg.MaskArgs), stats: make(map[string]*sqlStat)}
+}
+
+// sqlEntry how one statement is logged
+type sqlEntry struct {
+	level  zapcore.Level
+	args   []interface{} // masked, nil when there are too many to log
+	fields []zap.Field
+	skip   bool // sampled out
+}
+
+// observe
+/**
+ * @Description: record an execution and build its entry
+ * @receiver s
+ * @param sql
+ * @param args
+ * @param cost
+ * @param err
+ * @return sqlEntry
+ */
+func (s *sqlLogger) observe(sql string, args []interface{}, cost time.Duration, err error) sqlEntry {
+	slow := s.cfg.SlowThreshold > 0 && cost >= s.cfg.SlowThreshold
+	count := s.record(sqlFingerprint(sql), cost, err != nil, slow)
+
+	e := sqlEntry{level: zapcore.InfoLevel}
+	switch {
+	case err != nil:
+		e.level = zapcore.ErrorLevel
+	case slow:
+		e.level = zapcore.WarnLevel
+	case (count-1)%uint64(s.cfg.SampleFast) != 0:
+		e.skip = true
+		return e
+	}
+	query := sql
+	if len(query) > maxSQLLength {
+		query = query[:maxSQLLength]
+	}
+	e.fields = []zap.Field{
+		zap.String(Category.ToString(), "SQL"),
+		zap.Duration(Duration.ToString(), cost),
+		zap.String(QueryText.ToString(), query),
+	}
+	if slow {
+		e.fields = append(e.fields, zap.Bool("slow", true))
+	}
+	if err != nil {
+		e.fields = append(e.fields, zap.String(Errors.ToString(), err.Error()))
+	}
+	if len(args) < maxSQLArgs {
+		// with Config.Redact on, its field names mask args of those columns too
+		e.args = s.masker.mask(sql, args, GetProps().redactor)
+	}
+	return e
+}
+
+// record count an execution of fp, returns the executions of fp so far
+func (s *sqlLogger) record(fp string, cost time.Duration, failed, slow bool) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[fp]
+	if !ok {
+		if len(s.stats) >= maxSQLFingerprints {
+			fp = sqlOtherFingerprint
+			st = s.stats[fp]
+		}
+		if st == nil {
+			st = &sqlStat{}
+			s.stats[fp] = st
+		}
+	}
+	st.window[st.count%sqlStatWindow] = cost
+	st.count++
+	if failed {
+		st.errors++
+	}
+	if slow {
+		st.slow++
+	}
+	if cost > st.max {
+		st.max = cost
+	}
+	return st.count
+}
+
+// snapshot stats of every fingerprint, most executed first
+func (s *sqlLogger) snapshot() []SQLStat {
+	s.mu.Lock()
+	out := make([]SQLStat, 0, len(s.stats))
+	var window []time.Duration
+	for fp, st := range s.stats {
+		n := st.count
+		if n > sqlStatWindow {
+			n = sqlStatWindow
+		}
+		window = append(window[:0], st.window[:n]...)
+		sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+		out = append(out, SQLStat{
+			Fingerprint: fp,
+			Count:       st.count,
+			Errors:      st.errors,
+			Slow:        st.slow,
+			P50:         window[(len(window)-1)*50/100],
+			P99:         window[(len(window)-1)*99/100],
+			Max:         st.max,
+		})
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	return out
+}
+
+func (s *sqlLogger) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = make(map[string]*sqlStat)
+}
+
+// sqlStatsHandler serves snapshot as JSON
+func sqlStatsHandler(snapshot func() []SQLStat) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(snapshot())
+	})
+}
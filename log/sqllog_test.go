@@ -0,0 +1,139 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	xlog "xorm.io/xorm/log"
+)
+
+func TestSQLArgColumns(t *testing.T) {
+	cases := []struct {
+		sql   string
+		nargs int
+		want  []string
+	}{
+		{"SELECT * FROM `user` WHERE `u`.`Name` = ? AND password=? AND age > ?", 3, []string{"name", "password", "age"}},
+		{"SELECT * FROM t WHERE id IN (?, ?, ?) AND token LIKE ?", 4, []string{"id", "id", "id", "token"}},
+		{"SELECT * FROM t WHERE ts BETWEEN ? AND ? AND note = '?'", 2, []string{"ts", "ts"}},
+		{"UPDATE t SET secret = $2 WHERE id = $1", 2, []string{"id", "secret"}},
+		{"INSERT INTO `user` (`name`, `password`, created) VALUES (?, ?, NOW()), (?, ?, NOW())", 4, []string{"name", "password", "name", "password"}},
+		{"INSERT INTO t (a, b) VALUES (lower(?), ?)", 2, []string{"a", "b"}},
+		{"SELECT count(?) FROM t", 1, []string{""}},
+	}
+	for _, c := range cases {
+		if got := sqlArgColumns(c.sql, c.nargs); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s => %q, want %q", c.sql, got, c.want)
+		}
+	}
+}
+
+func TestSQLMasker(t *testing.T) {
+	m := newSQLMasker([]string{"Password"}, []int{2})
+	args := []interface{}{"bob", "s3cret", "token", 42}
+	got := m.mask("UPDATE user SET name = ?, password = ?, token = ? WHERE id = ?", args, nil)
+	if want := []interface{}{"bob", redactedValue, redactedValue, 42}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mask => %v", got)
+	}
+	if args[1] != "s3cret" {
+		t.Error("args of the caller changed")
+	}
+}
+
+func TestSQLFingerprint(t *testing.T) {
+	same := []string{
+		"SELECT * FROM `user` WHERE id IN (?, ?, ?) AND name = 'bob' LIMIT 10",
+		"select *  from `user`\n where id in (?,?) and name = 'alice' limit 20",
+	}
+	if a, b := sqlFingerprint(same[0]), sqlFingerprint(same[1]); a != b || a != "select * from `user` where id in (?+) and name = ? limit ?" {
+		t.Errorf("fingerprints => %q / %q", a, b)
+	}
+	if fp := sqlFingerprint("INSERT INTO t1 (a, b) VALUES ($1, $2), ($3, $4)"); fp != "insert into t1 (a, b) values (?+)" {
+		t.Errorf("insert => %q", fp)
+	}	// quoted identifiers name different tables, not values
+	users, orders := sqlFingerprint(`SELECT * FROM "users" WHERE "Id" = 1`), sqlFingerprint(`SELECT * FROM "orders" WHERE "Id" = 2`)
+	if users == orders || users != `select * from "users" where "Id" = ?` {
+		t.Errorf("quoted identifiers => %q / %q", users, orders)
+	}
+}
+
+func TestSQLLogger_Stats(t *testing.T) {
+	s := newSQLLogger(SQLLogConfig{SlowThreshold: 50 * time.Millisecond, SampleFast: 10})
+	logged := 0
+	for i := 1; i <= 100; i++ {
+		if e := s.observe("SELECT * FROM t WHERE id = ?", []interface{}{i}, time.Duration(i)*time.Millisecond/2, nil); !e.skip {
+			logged++
+		}
+	}
+	e := s.observe("SELECT * FROM t WHERE id = ?", nil, time.Second, errors.New("timeout"))
+	if e.skip || e.level != zapcore.ErrorLevel {
+		t.Errorf("failed statement => %+v", e)
+	}
+	// 1 in 10 of the fast ones plus every slow one
+	if logged != 10+1 {
+		t.Errorf("logged %d of 100", logged)
+	}
+	stats := s.snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("stats => %+v", stats)
+	}
+	st := stats[0]
+	if st.Count != 101 || st.Errors != 1 || st.Slow != 2 || st.Max != time.Second {
+		t.Errorf("stat => %+v", st)
+	}
+	if st.P50 != 25500*time.Microsecond || st.P99 != 50*time.Millisecond {
+		t.Errorf("percentiles => %s %s", st.P50, st.P99)
+	}
+}
+
+func TestOrmLoggerAdapter_AfterSQL(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json"}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	a := NewOrmLoggerAdapterByConfig(&SQLLogConfig{SlowThreshold: time.Second, MaskColumns: []string{"password"}})
+	ctx := context.WithValue(context.Background(), RequestIdKey, "r1")
+	a.AfterSQL(xlog.LogContext{Ctx: ctx, SQL: "SELECT * FROM user WHERE name = ? AND password = ?", Args: []interface{}{"bob", "s3cret"}, ExecuteTime: 2 * time.Second})
+	a.AfterSQL(xlog.LogContext{Ctx: ctx, SQL: "SELECT 1", ExecuteTime: time.Millisecond})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("output => %s", buf.String())
+	}
+	var slow map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &slow); err != nil {
+		t.Fatal(err)
+	}
+	if slow["level"] != "WARN" || slow["slow"] != true || slow[SessionId.ToString()] != "r1" {
+		t.Errorf("slow entry => %s", lines[0])
+	}
+	if msg := slow["msg"].(string); strings.Contains(msg, "s3cret") || !strings.Contains(msg, "[bob ***]") {
+		t.Errorf("args not masked => %s", msg)
+	}
+	if strings.Contains(lines[1], `"slow"`) || !strings.Contains(lines[1], `"level":"INFO"`) {
+		t.Errorf("fast entry => %s", lines[1])
+	}
+
+	rec := httptest.NewRecorder()
+	a.StatsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var stats []SQLStat
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || len(stats) != 2 {
+		t.Errorf("stats handler => %s", rec.Body.String())
+	}
+	a.ResetStats()
+	if len(a.Stats()) != 0 {
+		t.Error("stats not reset")
+	}
+}
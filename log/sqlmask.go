@@ -0,0 +1,235 @@
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// column compared with the placeholder at the end of the text before it:
+	// col = ?, col <> ?, col LIKE ?, col IN (?, ?, col BETWEEN ? AND ?
+	sqlColumnBefore  = regexp.MustCompile("(?i)([\\w.`\"]+)\\s*(?:=|<>|!=|<=|>=|<|>|\\s(?:not\\s+)?like|\\s(?:not\\s+)?in\\s*\\([^()]*|\\sbetween(?:\\s+\\S+\\s+and)?)\\s*$")
+	sqlInsertColumns = regexp.MustCompile("(?is)^\\s*(?:insert|replace)\\s+(?:ignore\\s+)?into\\s+[\\w.`\"]+\\s*\\(([^)]*)\\)\\s*values")
+	sqlInList        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	sqlTupleList     = regexp.MustCompile(`\(\?\+\)(?:\s*,\s*\(\?\+\))+`)
+)
+
+// sqlLookback text before a placeholder searched for its column
+const sqlLookback = 256
+
+// sqlPlaceholder a ? or $N outside quotes, arg is the index of the bound arg
+type sqlPlaceholder struct {
+	pos int
+	arg int
+}
+
+// sqlPlaceholders placeholders of sql in order
+func sqlPlaceholders(sql string) []sqlPlaceholder {
+	var (
+		out   []sqlPlaceholder
+		quote byte
+		next  int
+	)
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			out = append(out, sqlPlaceholder{pos: i, arg: next})
+			next++
+		case c == '$':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(sql[i+1 : j]); err == nil && n > 0 {
+				out = append(out, sqlPlaceholder{pos: i, arg: n - 1})
+				i = j - 1
+			}
+		}
+	}
+	return out
+}
+
+// sqlColumn bare lower case column name of an identifier like `u`.`Password`
+func sqlColumn(ident string) string {
+	ident = strings.TrimSpace(ident)
+	if i := strings.LastIndexByte(ident, '.'); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return strings.ToLower(strings.Trim(ident, "`\"[]"))
+}
+
+// sqlArgColumns column each arg is bound to, empty when unknown
+func sqlArgColumns(sql string, nargs int) []string {
+	cols := make([]string, nargs)
+	placeholders := sqlPlaceholders(sql)
+	if m := sqlInsertColumns.FindStringSubmatchIndex(sql); m != nil {
+		names := strings.Split(sql[m[2]:m[3]], ",")
+		insertValueColumns(sql, m[1], names, placeholders, cols)
+		return cols
+	}
+	for _, p := range placeholders {
+		if p.arg >= nargs {
+			continue
+		}
+		start := p.pos - sqlLookback
+		if start < 0 {
+			start = 0
+		}
+		if m := sqlColumnBefore.FindStringSubmatch(sql[start:p.pos]); m != nil {
+			cols[p.arg] = sqlColumn(m[1])
+		}
+	}
+	return cols
+}
+
+// insertValueColumns map placeholders of the VALUES tuples starting at from onto names by tuple position
+func insertValueColumns(sql string, from int, names []string, placeholders []sqlPlaceholder, cols []string) {
+	depth, col, next := 0, 0, 0
+	for i := from; i < len(sql) && next < len(placeholders); i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+			if depth == 1 {
+				col = 0
+			}
+		case ')':
+			depth--
+		case ',':
+			if depth == 1 {
+				col++
+			}
+		case '\'', '"', '`':
+			if end := strings.IndexByte(sql[i+1:], sql[i]); end >= 0 {
+				i += end + 1
+			}
+		}
+		if placeholders[next].pos == i {
+			p := placeholders[next]
+			next++
+			if depth >= 1 && col < len(names) && p.arg < len(cols) {
+				cols[p.arg] = sqlColumn(names[col])
+			}
+		}
+	}
+}
+
+// sqlMasker masks bound args by column name or position
+type sqlMasker struct {
+	columns   map[string]bool
+	positions map[int]bool
+}
+
+func newSQLMasker(columns []string, positions []int) *sqlMasker {
+	m := &sqlMasker{columns: make(map[string]bool, len(columns)), positions: make(map[int]bool, len(positions))}
+	for _, c := range columns {
+		m.columns[sqlColumn(c)] = true
+	}
+	for _, p := range positions {
+		m.positions[p] = true
+	}
+	return m
+}
+
+// mask copy of args with sensitive values replaced, args itself is left alone.
+// Columns the redactor r takes for sensitive fields are masked too, r may be nil.
+func (m *sqlMasker) mask(sql string, args []interface{}, r *Redactor) []interface{} {
+	if len(args) == 0 || (len(m.columns) == 0 && len(m.positions) == 0 && r == nil) {
+		return args
+	}
+	out := append([]interface{}(nil), args...)
+	for i := range out {
+		if m.positions[i] {
+			out[i] = redactedValue
+		}
+	}
+	if len(m.columns) > 0 || r != nil {
+		for i, col := range sqlArgColumns(sql, len(args)) {
+			if m.columns[col] || (r != nil && col != "" && r.Sensitive(col)) {
+				out[i] = redactedValue
+			}
+		}
+	}
+	return out
+}
+
+// sqlFingerprint
+/**
+ * @Description: statement shape shared by executions with different values:
+ * literals and placeholders become ?, IN lists and VALUES tuples collapse to
+ * (?+), keywords are lower cased and whitespace squeezed. Only single quotes
+ * are string literals, backtick and double quoted identifiers are kept as written.
+ * @param sql
+ * @return string
+ */
+func sqlFingerprint(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				end = len(sql) - i - 1
+			}
+			i += end + 1
+			b.WriteByte('?')
+		case c == '`' || c == '"':
+			// quoted identifier, case sensitive in some dialects
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				b.WriteString(sql[i:])
+				return b.String()
+			}
+			b.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case c == '$' || (c >= '0' && c <= '9' && !identByte(prevByte(sql, i))):
+			j := i + 1
+			for j < len(sql) && (sql[j] >= '0' && sql[j] <= '9' || sql[j] == '.') {
+				j++
+			}
+			if c == '$' && j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			i = j - 1
+			b.WriteByte('?')
+		default:
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			b.WriteByte(c)
+		}
+	}
+	fp := sqlInList.ReplaceAllString(b.String(), "(?+)")
+	return sqlTupleList.ReplaceAllString(fp, "(?+)")
+}
+
+func identByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func prevByte(s string, i int) byte {
+	if i == 0 {
+		return ' '
+	}
+	return s[i-1]
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultSyslogAddress = "/dev/log"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogConfig
+/**
+ * @Description: serializes syslog sink config in toml/json/yaml.
+ */
+type SyslogConfig struct {
+	Network  string `toml:"network" json:"network" yaml:"network"`    // unixgram or unix, default unixgram.
+	Address  string `toml:"address" json:"address" yaml:"address"`    // Socket path, default /dev/log.
+	Facility string `toml:"facility" json:"facility" yaml:"facility"` // kern, user, daemon, local0-7..., default user.
+	AppName  string `toml:"appName" json:"appName" yaml:"appName"`    // APP-NAME of each message, default the program name.
+}
+
+// Validate
+/**
+ * @Description: check network and facility
+ * @receiver c
+ * @return error
+ */
+func (c *SyslogConfig) Validate() error {
+	switch c.Network {
+	case "", "unixgram", "unix":
+	default:
+		return fmt.Errorf("log.sinks.syslog: unknown network '%s'", c.Network)
+	}
+	if _, ok := syslogFacilities[c.Facility]; c.Facility != "" && !ok {
+		return fmt.Errorf("log.sinks.syslog: unknown facility '%s'", c.Facility)
+	}
+	return nil
+}
+
+// SyslogWriter
+/**
+ * @Description: RFC 5424 messages to the local syslog socket, the entry level
+ * becomes the severity. Stream sockets use octet counting framing (RFC 6587).
+ */
+type SyslogWriter struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	appName  string
+	pid      string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter
+/**
+ * @Description: connect to the syslog socket described by cfg
+ * @param cfg
+ * @return *SyslogWriter
+ * @return error
+ */
+func NewSyslogWriter(cfg SyslogConfig) (*SyslogWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	w := &SyslogWriter{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: syslogFacilities["user"],
+		appName:  cfg.AppName,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if w.network == "" {
+		w.network = "unixgram"
+	}
+	if w.address == "" {
+		w.address = defaultSyslogAddress
+	}
+	if cfg.Facility != "" {
+		w.facility = syslogFacilities[cfg.Facility]
+	}
+	if w.appName == "" {
+		w.appName = filepath.Base(os.Args[0])
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write implements io.Writer, entries written without level count as info
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zapcore.InfoLevel, p)
+}
+
+// WriteLevel implements LevelWriter
+func (w *SyslogWriter) WriteLevel(lvl zapcore.Level, p []byte) (int, error) {
+	msg := w.format(lvl, time.Now(), p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		// syslogd restarted, reconnect once
+		_ = w.conn.Close()
+		w.conn = nil
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// format <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) format(lvl zapcore.Level, t time.Time, p []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s - - ", w.facility*8+syslogSeverity(lvl),
+		t.UTC().Format("2006-01-02T15:04:05.000000Z"), w.hostname, w.appName, w.pid)
+	buf.Write(bytes.TrimRight(p, "\n"))
+	if w.network == "unix" {
+		return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+	}
+	return buf.Bytes()
+}
+
+// Sync implements zapcore.WriteSyncer, every write is already sent
+func (w *SyslogWriter) Sync() error {
+	return nil
+}
+
+// Close the socket
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogSeverity RFC 5424 severity of lvl
+func syslogSeverity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,219 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// W3C trace context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// FlagSampled trace-flags bit telling downstream services the trace is recorded
+const FlagSampled byte = 0x01
+
+const (
+	traceparentLen = 55
+	// maxTracestateLen longer tracestate values are dropped rather than truncated mid member
+	maxTracestateLen = 512
+)
+
+var errTraceparent = errors.New("log: invalid traceparent")
+
+type traceCtxKey struct{}
+
+// TraceContext
+/**
+ * @Description: W3C trace context of a request. SpanID is the span of this
+ * service, ParentID the span of the caller, empty when the trace starts here.
+ */
+type TraceContext struct {
+	TraceID  string // 32 lower case hex digits
+	SpanID   string // 16 lower case hex digits
+	ParentID string
+	Flags    byte
+	State    string // tracestate, passed on untouched
+}
+
+// ParseTraceparent
+/**
+ * @Description: parse a traceparent header, the parent-id becomes ParentID.
+ * Versions above 00 are read as 00 as the spec requires, ff and all zero ids are rejected.
+ * @param header
+ * @return TraceContext
+ * @return error
+ */
+func ParseTraceparent(header string) (TraceContext, error) {
+	h := strings.TrimSpace(header)
+	if len(h) < traceparentLen || h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return TraceContext{}, errTraceparent
+	}
+	version := h[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceContext{}, errTraceparent
+	}
+	if len(h) > traceparentLen && (version == "00" || h[traceparentLen] != '-') {
+		return TraceContext{}, errTraceparent
+	}
+	traceID, parentID, flags := h[3:35], h[36:52], h[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) || isZeroHex(traceID) || isZeroHex(parentID) {
+		return TraceContext{}, errTraceparent
+	}
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, ParentID: parentID, Flags: b[0]}, nil
+}
+
+// NewTraceContext
+/**
+ * @Description: new trace rooted at this service, sampled
+ * @return TraceContext
+ */
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: NewTraceID(), SpanID: NewSpanID(), Flags: FlagSampled}
+}
+
+// NewTraceID random 16 byte trace id in hex
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID random 8 byte span id in hex
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+// Valid trace and span ids are well formed and not all zero
+func (tc TraceContext) Valid() bool {
+	return len(tc.TraceID) == 32 && isLowerHex(tc.TraceID) && !isZeroHex(tc.TraceID) &&
+		len(tc.SpanID) == 16 && isLowerHex(tc.SpanID) && !isZeroHex(tc.SpanID)
+}
+
+// Sampled reports the sampled trace-flag
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&FlagSampled != 0
+}
+
+// Traceparent header naming SpanID as the parent of the next hop
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// Child trace context of an outgoing call made from tc
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{TraceID: tc.TraceID, SpanID: NewSpanID(), ParentID: tc.SpanID, Flags: tc.Flags, State: tc.State}
+}
+
+// ContextWithTrace
+/**
+ * @Description: store tc in ctx, also under the TraceId and SpanId keys
+ * @param ctx
+ * @param tc
+ * @return context.Context
+ */
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	ctx = context.WithValue(ctx, traceCtxKey{}, tc)
+	ctx = context.WithValue(ctx, TraceId, tc.TraceID)
+	return context.WithValue(ctx, SpanId, tc.SpanID)
+}
+
+// TraceFromContext
+/**
+ * @Description: trace context stored by ContextWithTrace
+ * @param ctx
+ * @return TraceContext
+ * @return bool
+ */
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(traceCtxKey{}).(TraceContext)
+	return tc, ok
+}
+
+// InjectTraceparent
+/**
+ * @Description: set traceparent and tracestate on an outgoing request so the
+ * callee continues the trace of ctx, nothing is set when ctx carries no trace
+ * @param ctx
+ * @param header
+ */
+func InjectTraceparent(ctx context.Context, header http.Header) {
+	tc, ok := TraceFromContext(ctx)
+	if !ok || !tc.Valid() {
+		return
+	}
+	header.Set(TraceparentHeader, tc.Traceparent())
+	if tc.State != "" {
+		header.Set(TracestateHeader, tc.State)
+	}
+}
+
+// extractTrace read the trace context of an incoming request, a fresh trace
+// when traceparent is missing or malformed
+func extractTrace(header http.Header) TraceContext {
+	tc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return NewTraceContext()
+	}
+	tc.SpanID = NewSpanID()
+	// tracestate is only meaningful alongside a valid traceparent
+	state := strings.Join(header.Values(TracestateHeader), ",")
+	if len(state) <= maxTracestateLen {
+		tc.State = strings.TrimSpace(state)
+	}
+	return tc
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(fmt.Errorf("log: read random id: %w", err))
+		}
+		if !isZeroBytes(b) {
+			return hex.EncodeToString(b)
+		}
+	}
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+func isZeroBytes(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Tracer
+/**
+ * @Description: bridges GinLogger to a tracing library so spans and log lines
+ * share their ids, see log/otellog for OpenTelemetry. Start begins the server
+ * span of a request whose incoming context is remote (ParentID empty for a new
+ * trace) and returns the context of the started span and a func ending it.
+ */
+type Tracer interface {
+	Start(ctx context.Context, name string, remote TraceContext) (context.Context, TraceContext, func())
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.ParentID != "00f067aa0ba902b7" || !tc.Sampled() {
+		t.Errorf("parsed => %+v", tc)
+	}
+	// future versions may append fields
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Error(err)
+	}
+	for _, h := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(h); err == nil {
+			t.Errorf("%q accepted", h)
+		}
+	}
+}
+
+func TestInjectTraceparent(t *testing.T) {
+	h := http.Header{}
+	InjectTraceparent(context.Background(), h)
+	if len(h) != 0 {
+		t.Errorf("untraced ctx injected %v", h)
+	}
+	tc := NewTraceContext()
+	tc.State = "vendor=1"
+	InjectTraceparent(ContextWithTrace(context.Background(), tc), h)
+	if got, err := ParseTraceparent(h.Get(TraceparentHeader)); err != nil || got.TraceID != tc.TraceID || got.ParentID != tc.SpanID {
+		t.Errorf("traceparent => %q %v", h.Get(TraceparentHeader), err)
+	}
+	if h.Get(TracestateHeader) != "vendor=1" {
+		t.Errorf("tracestate => %q", h.Get(TracestateHeader))
+	}
+}
+
+func TestGinLogger_Traceparent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "info", StdLevel: "critical", Format: "json"}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	defer New(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinLogger())
+	var handlerFields map[string]interface{}
+	r.GET("/ping", func(c *gin.Context) {
+		handlerFields = fieldMap(Ctx2Fields(c.Request.Context()))
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TracestateHeader, "congo=t61rcWkgMzE")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	out, err := ParseTraceparent(rec.Header().Get(TraceparentHeader))
+	if err != nil || out.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || out.ParentID == "00f067aa0ba902b7" {
+		t.Fatalf("response traceparent => %q", rec.Header().Get(TraceparentHeader))
+	}
+	if rec.Header().Get(TracestateHeader) != "congo=t61rcWkgMzE" {
+		t.Errorf("tracestate => %q", rec.Header().Get(TracestateHeader))
+	}
+	if handlerFields[TraceId.ToString()] != out.TraceID || handlerFields[SpanId.ToString()] != out.ParentID {
+		t.Errorf("handler ctx fields => %v", handlerFields)
+	}
+
+	var access map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &access); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if access[TraceId.ToString()] != out.TraceID || access[ParentSpanId.ToString()] != "00f067aa0ba902b7" {
+		t.Errorf("access log => %v", access)
+	}
+
+	// without traceparent a new trace starts
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if fresh, err := ParseTraceparent(rec.Header().Get(TraceparentHeader)); err != nil || fresh.TraceID == out.TraceID {
+		t.Errorf("new trace => %q %v", rec.Header().Get(TraceparentHeader), err)
+	}
+}
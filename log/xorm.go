@@ -1,67 +1,223 @@
 package log
 
 import (
+	"context"
 	"fmt"
-	"go.uber.org/zap"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	xlog "xorm.io/xorm/log"
 )
 
+// _gOrmLogger output of the xorm loggers, named xorm so its level can be set
+// through Config.Modules, the skip reports the xorm call site as caller
+var _gOrmLogger = Named("xorm").WithOptions(zap.AddCallerSkip(2))
+
+var (
+	SessionIDKey      = "__xorm_session_id"
+	SessionKey        = "__xorm_session_key"
+	SessionShowSQLKey = "__xorm_show_sql"
+)
+
+// ormSwitches
+/**
+ * @Description: xorm level and show SQL toggle shared by the xorm loggers,
+ * the zero value logs everything at LOG_DEBUG and shows SQL
+ */
+type ormSwitches struct {
+	level   int32  // xlog.LogLevel, switched while sessions log
+	hideSQL uint32 // 1 hides SQL
+}
+
+// Level implements xorm Logger
+func (s *ormSwitches) Level() xlog.LogLevel {
+	return xlog.LogLevel(atomic.LoadInt32(&s.level))
+}
+
+// SetLevel implements xorm Logger, LOG_OFF silences the logger
+func (s *ormSwitches) SetLevel(lv xlog.LogLevel) {
+	atomic.StoreInt32(&s.level, int32(lv))
+}
+
+// ShowSQL implements xorm Logger, no argument means true
+func (s *ormSwitches) ShowSQL(show ...bool) {
+	var hide uint32
+	if len(show) > 0 && !show[0] {
+		hide = 1
+	}
+	atomic.StoreUint32(&s.hideSQL, hide)
+}
+
+// IsShowSQL implements xorm Logger
+func (s *ormSwitches) IsShowSQL() bool {
+	return atomic.LoadUint32(&s.hideSQL) == 0
+}
+
+// showSQL a bool under SessionShowSQLKey in ctx wins over IsShowSQL, like xorm does
+func (s *ormSwitches) showSQL(ctx context.Context) bool {
+	if ctx != nil {
+		if show, ok := ctx.Value(SessionShowSQLKey).(bool); ok {
+			return show
+		}
+	}
+	return s.IsShowSQL()
+}
+
+// enabled entries at lvl pass the xorm level
+func (s *ormSwitches) enabled(lvl zapcore.Level) bool {
+	return xormLevel(lvl) >= s.Level()
+}
+
+// log v joined like xorm does at lvl, joining is skipped when the entry is dropped
+func (s *ormSwitches) log(lvl zapcore.Level, v []interface{}) {
+	if !s.enabled(lvl) || !_gOrmLogger.Core().Enabled(lvl) {
+		return
+	}
+	if ce := _gOrmLogger.Check(lvl, Interfaces2String(v...)); ce != nil {
+		ce.Write()
+	}
+}
+
+// logf formatted message at lvl, formatting is skipped when the entry is dropped
+func (s *ormSwitches) logf(lvl zapcore.Level, format string, v []interface{}) {
+	if !s.enabled(lvl) || !_gOrmLogger.Core().Enabled(lvl) {
+		return
+	}
+	if ce := _gOrmLogger.Check(lvl, fmt.Sprintf(format, v...)); ce != nil {
+		ce.Write()
+	}
+}
+
+// afterSQL log a statement through sl when SQL is shown and its level passes,
+// Stats count it either way like GormLogger does
+func (s *ormSwitches) afterSQL(sl *sqlLogger, ctx xlog.LogContext) {
+	e := sl.observe(ctx.SQL, ctx.Args, ctx.ExecuteTime, ctx.Err)
+	if e.skip || !s.showSQL(ctx.Ctx) || !s.enabled(e.level) {
+		return
+	}
+	var sessionPart string
+	var fields []zap.Field
+	if ctx.Ctx != nil {
+		if key, ok := ctx.Ctx.Value(SessionIDKey).(string); ok {
+			sessionPart = fmt.Sprintf(" [%s]", key)
+		}
+		fields = Ctx2Fields(ctx.Ctx)
+	}
+	var rows int64
+	var rowErr error
+	if ctx.Result != nil {
+		rows, rowErr = ctx.Result.RowsAffected()
+	}
+	msg := fmt.Sprintf("[SQL]Part: %s Args: %v Rows %d Err %s", sessionPart, e.args, rows, rowErr)
+	if ce := _gOrmLogger.Check(e.level, msg); ce != nil {
+		ce.Write(append(fields, e.fields...)...)
+	}
+}
+
+// xormLevel xorm level of a zap level
+func xormLevel(lvl zapcore.Level) xlog.LogLevel {
+	switch {
+	case lvl >= zapcore.ErrorLevel:
+		return xlog.LOG_ERR
+	case lvl == zapcore.WarnLevel:
+		return xlog.LOG_WARNING
+	case lvl == zapcore.InfoLevel:
+		return xlog.LOG_INFO
+	default:
+		return xlog.LOG_DEBUG
+	}
+}
+
+// ormSQL statement logging state, created on first use when not configured
+type ormSQL struct {
+	once sync.Once
+	sql  *sqlLogger
+}
+
+func (o *ormSQL) sqlLog() *sqlLogger {
+	o.once.Do(func() {
+		if o.sql == nil {
+			o.sql = newSQLLogger(SQLLogConfig{})
+		}
+	})
+	return o.sql
+}
+
 type OrmLogger struct {
-	level xlog.LogLevel
+	ormSwitches
 }
 
 func (l *OrmLogger) Debug(v ...interface{}) {
-	zap.L().Debug(Interfaces2String(v))
+	l.log(zapcore.DebugLevel, v)
 }
 func (l *OrmLogger) Debugf(format string, v ...interface{}) {
-	zap.L().Debug(fmt.Sprintf(format, v...))
+	l.logf(zapcore.DebugLevel, format, v)
 }
 func (l *OrmLogger) Error(v ...interface{}) {
-	zap.L().Error(Interfaces2String(v))
+	l.log(zapcore.ErrorLevel, v)
 }
 func (l *OrmLogger) Errorf(format string, v ...interface{}) {
-	zap.L().Error(fmt.Sprintf(format, v...))
+	l.logf(zapcore.ErrorLevel, format, v)
 }
 func (l *OrmLogger) Info(v ...interface{}) {
-	zap.L().Info(Interfaces2String(v))
+	l.log(zapcore.InfoLevel, v)
 }
 func (l *OrmLogger) Infof(format string, v ...interface{}) {
-	zap.L().Info(fmt.Sprintf(format, v...))
+	l.logf(zapcore.InfoLevel, format, v)
 }
 func (l *OrmLogger) Warn(v ...interface{}) {
-	zap.L().Warn(Interfaces2String(v))
+	l.log(zapcore.WarnLevel, v)
 }
 func (l *OrmLogger) Warnf(format string, v ...interface{}) {
-	zap.L().Warn(fmt.Sprintf(format, v...))
+	l.logf(zapcore.WarnLevel, format, v)
 }
 
-func (l *OrmLogger) Level() xlog.LogLevel {
-	return l.level
-}
-func (l *OrmLogger) SetLevel(level xlog.LogLevel) {
-	l.level = level
+type OrmLoggerAdapter struct {
+	ormSwitches
+	ormSQL
 }
 
-func (l *OrmLogger) ShowSQL(show ...bool) {
-
+func NewOrmLoggerAdapter() xlog.ContextLogger {
+	return NewOrmLoggerAdapterByConfig(&SQLLogConfig{})
 }
-func (l *OrmLogger) IsShowSQL() bool {
-	return true
+
+// NewOrmLoggerAdapterByConfig
+/**
+ * @Description: xorm logger with slow query detection, arg masking and sampling as configured by cfg
+ * @param cfg
+ * @return *OrmLoggerAdapter
+ */
+func NewOrmLoggerAdapterByConfig(cfg *SQLLogConfig) *OrmLoggerAdapter {
+	return &OrmLoggerAdapter{ormSQL: ormSQL{sql: newSQLLogger(*cfg)}}
 }
 
-var (
-	SessionIDKey      = "__xorm_session_id"
-	SessionKey        = "__xorm_session_key"
-	SessionShowSQLKey = "__xorm_show_sql"
-)
+// Stats
+/**
+ * @Description: count, errors and latency percentiles per statement fingerprint, most executed first
+ * @receiver l
+ * @return []SQLStat
+ */
+func (l *OrmLoggerAdapter) Stats() []SQLStat {
+	return l.sqlLog().snapshot()
+}
 
-type OrmLoggerAdapter struct {
-	level xlog.LogLevel
+// ResetStats forget the stats collected so far
+func (l *OrmLoggerAdapter) ResetStats() {
+	l.sqlLog().reset()
 }
 
-func NewOrmLoggerAdapter() xlog.ContextLogger {
-	return &OrmLoggerAdapter{}
+// StatsHandler
+/**
+ * @Description: serves Stats as JSON, e.g. mounted on an admin port
+ * @receiver l
+ * @return http.Handler
+ */
+func (l *OrmLoggerAdapter) StatsHandler() http.Handler {
+	return sqlStatsHandler(l.Stats)
 }
 
 // BeforeSQL implements ContextLogger
@@ -69,116 +225,66 @@ func (l *OrmLoggerAdapter) BeforeSQL(ctx xlog.LogContext) {}
 
 // AfterSQL implements ContextLogger
 func (l *OrmLoggerAdapter) AfterSQL(ctx xlog.LogContext) {
-	var sessionPart string
-	v := ctx.Ctx.Value(SessionIDKey)
-	if key, ok := v.(string); ok {
-		sessionPart = fmt.Sprintf(" [%s]", key)
-	}
-	fields := Ctx2Fields(ctx.Ctx)
-	fields = append(fields, zap.String(Category.ToString(), "SQL"), zap.Duration(Duration.ToString(), ctx.ExecuteTime))
-
-	if len(ctx.SQL) > 2048 {
-		fields = append(fields, zap.String(QueryText.ToString(), ctx.SQL[0:2048]))
-	} else {
-		fields = append(fields, zap.String(QueryText.ToString(), ctx.SQL))
-	}
-	args := make([]interface{}, 0)
-	if len(ctx.Args) < 100 {
-		args = ctx.Args
-	}
-	var rows int64
-	var rowErr error
-	if ctx.Result != nil {
-		rows, rowErr = ctx.Result.RowsAffected()
-	}
-	msg := fmt.Sprintf("[SQL]Part: %s Args: %v Rows %d Err %s", sessionPart, args, rows, rowErr)
-	// errors
-	if ctx.Err != nil {
-		fields = append(fields, zap.String(Errors.ToString(), ctx.Err.Error()))
-		GetLogger().Error(msg, fields...)
-	} else {
-		GetLogger().Info(msg, fields...)
-	}
+	l.afterSQL(l.sqlLog(), ctx)
 }
 
 // Debugf implements ContextLogger
 func (l *OrmLoggerAdapter) Debugf(format string, v ...interface{}) {
-	zap.L().Debug(fmt.Sprintf(format, v...))
+	l.logf(zapcore.DebugLevel, format, v)
 }
 
 // Errorf implements ContextLogger
 func (l *OrmLoggerAdapter) Errorf(format string, v ...interface{}) {
-	zap.L().Error(fmt.Sprintf(format, v...))
+	l.logf(zapcore.ErrorLevel, format, v)
 }
 
 // Infof implements ContextLogger
 func (l *OrmLoggerAdapter) Infof(format string, v ...interface{}) {
-	zap.L().Info(fmt.Sprintf(format, v...))
+	l.logf(zapcore.InfoLevel, format, v)
 }
 
 // Warnf implements ContextLogger
 func (l *OrmLoggerAdapter) Warnf(format string, v ...interface{}) {
-	zap.L().Warn(fmt.Sprintf(format, v...))
-}
-
-// Level implements ContextLogger
-func (l *OrmLoggerAdapter) Level() xlog.LogLevel {
-	return l.level
-}
-
-// SetLevel implements ContextLogger
-func (l *OrmLoggerAdapter) SetLevel(lv xlog.LogLevel) {
-	l.level = lv
-}
-
-// ShowSQL implements ContextLogger
-func (l *OrmLoggerAdapter) ShowSQL(show ...bool) {
-
-}
-
-// IsShowSQL implements ContextLogger
-func (l *OrmLoggerAdapter) IsShowSQL() bool {
-	return true
+	l.logf(zapcore.WarnLevel, format, v)
 }
 
 type OrmCtxLogger struct {
-	level xlog.LogLevel
+	ormSwitches
+	ormSQL
 }
 
 func (l *OrmCtxLogger) BeforeSQL(context xlog.LogContext) {}
-func (l *OrmCtxLogger) AfterSQL(context xlog.LogContext)  {}
+func (l *OrmCtxLogger) AfterSQL(context xlog.LogContext) {
+	l.afterSQL(l.sqlLog(), context)
+}
 
 func (l *OrmCtxLogger) Debugf(format string, v ...interface{}) {
-	zap.L().Debug(fmt.Sprintf(format, v...))
+	l.logf(zapcore.DebugLevel, format, v)
 }
 func (l *OrmCtxLogger) Errorf(format string, v ...interface{}) {
-	zap.L().Error(fmt.Sprintf(format, v...))
+	l.logf(zapcore.ErrorLevel, format, v)
 }
 func (l *OrmCtxLogger) Infof(format string, v ...interface{}) {
-	zap.L().Info(fmt.Sprintf(format, v...))
+	l.logf(zapcore.InfoLevel, format, v)
 }
 func (l *OrmCtxLogger) Warnf(format string, v ...interface{}) {
-	zap.L().Warn(fmt.Sprintf(format, v...))
-}
-
-func (l *OrmCtxLogger) Level() xlog.LogLevel {
-	return l.level
-}
-
-func (l *OrmCtxLogger) SetLevel(level xlog.LogLevel) {
-	l.level = level
-}
-
-func (l *OrmCtxLogger) ShowSQL(show ...bool) {}
-
-func (l *OrmCtxLogger) IsShowSQL() bool {
-	return true
+	l.logf(zapcore.WarnLevel, format, v)
 }
 
+// Interfaces2String
+/**
+ * @Description: concatenate v, strings as is and other values as fmt.Print does
+ * @param v
+ * @return string
+ */
 func Interfaces2String(v ...interface{}) string {
 	var sb strings.Builder
 	for _, i := range v {
-		sb.WriteString(i.(string))
+		if s, ok := i.(string); ok {
+			sb.WriteString(s)
+		} else {
+			fmt.Fprint(&sb, i)
+		}
 	}
 	return sb.String()
 }
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	xlog "xorm.io/xorm/log"
+)
+
+var (
+	_ xlog.Logger        = &OrmLogger{}
+	_ xlog.ContextLogger = &OrmLoggerAdapter{}
+	_ xlog.ContextLogger = &OrmCtxLogger{}
+)
+
+func xormOutput(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "debug", StdLevel: "critical", Format: "json"}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+	return buf
+}
+
+func xormLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	buf.Reset()
+	return out
+}
+
+func TestOrmLogger_Level(t *testing.T) {
+	buf := xormOutput(t)
+	l := &OrmLogger{}
+	l.Debug("a", 1, errors.New("b"))
+	lines := xormLines(t, buf)
+	if len(lines) != 1 || lines[0]["msg"] != "a1b" || lines[0]["logger"] != "xorm" {
+		t.Fatalf("debug => %v", lines)
+	}
+	if caller, _ := lines[0]["caller"].(string); !strings.HasPrefix(caller, "log/xorm_test.go") {
+		t.Errorf("caller => %s", caller)
+	}
+
+	l.SetLevel(xlog.LOG_WARNING)
+	l.Info("dropped")
+	l.Infof("dropped %d", 1)
+	l.Warnf("kept %d", 1)
+	l.Error("kept")
+	lines = xormLines(t, buf)
+	if len(lines) != 2 || lines[0]["msg"] != "kept 1" || lines[1]["level"] != "ERROR" {
+		t.Errorf("warning => %v", lines)
+	}
+
+	l.SetLevel(xlog.LOG_OFF)
+	l.Errorf("dropped")
+	if lines = xormLines(t, buf); len(lines) != 0 {
+		t.Errorf("off => %v", lines)
+	}
+
+	if err := SetModuleLevel("xorm", "error"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetModuleLevel("xorm", "") //nolint:errcheck
+	l.SetLevel(xlog.LOG_DEBUG)
+	l.Warn("dropped by module level")
+	if lines = xormLines(t, buf); len(lines) != 0 {
+		t.Errorf("module level => %v", lines)
+	}
+}
+
+func TestOrmLoggerAdapter_ShowSQL(t *testing.T) {
+	buf := xormOutput(t)
+	for _, l := range []xlog.ContextLogger{NewOrmLoggerAdapter(), &OrmCtxLogger{}} {
+		sql := xlog.LogContext{Ctx: context.Background(), SQL: "SELECT 1"}
+		if !l.IsShowSQL() {
+			t.Fatal("SQL hidden by default")
+		}
+		l.AfterSQL(sql)
+		if lines := xormLines(t, buf); len(lines) != 1 || lines[0]["logger"] != "xorm" || lines[0][QueryText.ToString()] != "SELECT 1" {
+			t.Errorf("%T shown => %v", l, lines)
+		}
+
+		l.ShowSQL(false)
+		l.AfterSQL(sql)
+		if lines := xormLines(t, buf); len(lines) != 0 || l.IsShowSQL() {
+			t.Errorf("%T hidden => %v", l, lines)
+		}
+		l.AfterSQL(xlog.LogContext{Ctx: context.WithValue(context.Background(), SessionShowSQLKey, true), SQL: "SELECT 2"})
+		if lines := xormLines(t, buf); len(lines) != 1 {
+			t.Errorf("%T session override => %v", l, lines)
+		}
+		l.ShowSQL()
+
+		l.SetLevel(xlog.LOG_WARNING)
+		l.AfterSQL(sql)
+		l.AfterSQL(xlog.LogContext{Ctx: context.Background(), SQL: "SELECT 3", Err: errors.New("boom")})
+		if lines := xormLines(t, buf); len(lines) != 1 || lines[0]["level"] != "ERROR" {
+			t.Errorf("%T level => %v", l, lines)
+		}
+	}
+}
+
+func TestOrmLogger_ConcurrentSwitches(t *testing.T) {
+	xormOutput(t)
+	l := NewOrmLoggerAdapterByConfig(&SQLLogConfig{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			l.SetLevel(xlog.LogLevel(i % 3))
+			l.ShowSQL(i%2 == 0)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.Infof("%d", i)
+		l.AfterSQL(xlog.LogContext{Ctx: context.Background(), SQL: "SELECT 1"})
+	}
+	<-done
+}
+
+func TestOrmLoggerAdapter_RedactFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, p, err := InitLoggerWithWriteSyncer(&Config{Level: "debug", StdLevel: "critical", Format: "json",
+		Redact: RedactConfig{Enable: true, Fields: []string{"password"}}}, zapcore.AddSync(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Reset(l, p)
+	t.Cleanup(func() { New(nil) })
+
+	// no MaskColumns, and the value matches no pattern
+	NewOrmLoggerAdapter().AfterSQL(xlog.LogContext{Ctx: context.Background(), SQL: "UPDATE user SET password = ? WHERE id = ?", Args: []interface{}{"hunter2", 7}})
+	lines := xormLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("lines => %v", lines)
+	}
+	if msg := lines[0]["msg"].(string); strings.Contains(msg, "hunter2") || !strings.Contains(msg, "Args: [*** 7]") {
+		t.Errorf("msg => %s", msg)
+	}
+}
+
+func TestOrmLoggerAdapter_StatsWithoutShowSQL(t *testing.T) {
+	buf := xormOutput(t)
+	l := NewOrmLoggerAdapterByConfig(&SQLLogConfig{})
+	l.ShowSQL(false)
+	l.AfterSQL(xlog.LogContext{Ctx: context.Background(), SQL: "SELECT 1"})
+	l.AfterSQL(xlog.LogContext{Ctx: context.Background(), SQL: "SELECT 2", Err: errors.New("boom")})
+	if lines := xormLines(t, buf); len(lines) != 0 {
+		t.Errorf("hidden SQL logged => %v", lines)
+	}
+	if stats := l.Stats(); len(stats) != 1 || stats[0].Count != 2 || stats[0].Errors != 1 {
+		t.Errorf("stats => %+v", stats)
+	}
+}